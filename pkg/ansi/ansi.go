@@ -69,7 +69,13 @@ func ColorizeJSON(json string, darkStyle bool, w io.Writer) string {
 
 // ColorizeStatus returns a colorized number for HTTP status code
 func ColorizeStatus(status int) aurora.Value {
-	color := Color(os.Stdout)
+	return ColorizeStatusFor(status, os.Stdout)
+}
+
+// ColorizeStatusFor returns a colorized number for HTTP status code, if the
+// writer supports colors.
+func ColorizeStatusFor(status int, w io.Writer) aurora.Value {
+	color := Color(w)
 
 	switch {
 	case status >= 500:
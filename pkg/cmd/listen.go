@@ -4,13 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/stripe/stripe-cli/pkg/listenui"
 	"github.com/stripe/stripe-cli/pkg/proxy"
 	"github.com/stripe/stripe-cli/pkg/requests"
 	"github.com/stripe/stripe-cli/pkg/stripe"
@@ -34,6 +40,24 @@ type listenCmd struct {
 	printJSON             bool
 	skipVerify            bool
 	onlyPrintSecret       bool
+	journalFile           string
+	retries               int
+	retryBackoff          time.Duration
+	routesFile            string
+	signingSecret         string
+	timeout               time.Duration
+	maxConcurrency        int
+	ordered               bool
+	tui                   bool
+	localPort             int
+	localOnly             bool
+	shutdownTimeout       time.Duration
+	reconnectForever      bool
+	backfill              bool
+	transformsFile        string
+	filters               []string
+	format                string
+	showResponses         bool
 
 	apiBaseURL string
 	noWSS      bool
@@ -52,21 +76,45 @@ API version, filter events, or even load your saved webhook endpoints from your
 Stripe account.`,
 		Example: `stripe listen
   stripe listen --events charge.captured,charge.updated \
-    --forward-to localhost:3000/events`,
+    --forward-to localhost:3000/events
+  stripe listen --routes routes.toml
+  stripe listen --forward-to "exec:./bin/process-event"
+  stripe listen --filter "data.object.metadata.developer == jenny"
+  stripe listen --local-port 9000 --local-only --forward-to localhost:3000/events`,
 		RunE: lc.runListenCmd,
 	}
 
+	lc.cmd.AddCommand(newListenReplayCmd().cmd)
+
 	lc.cmd.Flags().StringSliceVar(&lc.forwardConnectHeaders, "connect-headers", []string{}, "A comma-separated list of custom headers to forward for Connect")
-	lc.cmd.Flags().StringSliceVarP(&lc.events, "events", "e", []string{"*"}, "A comma-separated list of specific events to listen for. For a list of all possible events, see: https://stripe.com/docs/api/events/types")
-	lc.cmd.Flags().StringVarP(&lc.forwardURL, "forward-to", "f", "", "The URL to forward webhook events to")
+	lc.cmd.Flags().StringSliceVarP(&lc.events, "events", "e", []string{"*"}, "A comma-separated list of specific events to listen for. Wildcards (invoice.*) and negations (!charge.updated) are supported. For a list of all possible events, see: https://stripe.com/docs/api/events/types")
+	lc.cmd.Flags().StringVarP(&lc.forwardURL, "forward-to", "f", "", "The URL to forward webhook events to, or an exec:<command>, file:<path> or unix:<socket>[:<path>] destination")
 	lc.cmd.Flags().StringSliceVarP(&lc.forwardHeaders, "headers", "H", []string{}, "A comma-separated list of custom headers to forward")
 	lc.cmd.Flags().StringVarP(&lc.forwardConnectURL, "forward-connect-to", "c", "", "The URL to forward Connect webhook events to (default: same as normal events)")
 	lc.cmd.Flags().BoolVarP(&lc.latestAPIVersion, "latest", "l", false, "Receive events formatted with the latest API version (default: your account's default API version)")
 	lc.cmd.Flags().BoolVar(&lc.livemode, "live", false, "Receive live events (default: test)")
+	lc.cmd.Flags().StringVar(&lc.format, "format", "", "Specifies the output format. Use \"jsonl\" to print one JSON record per line for every session, event, delivery attempt and response")
 	lc.cmd.Flags().BoolVarP(&lc.printJSON, "print-json", "j", false, "Print full JSON objects to stdout")
 	lc.cmd.Flags().BoolVarP(&lc.loadFromWebhooksAPI, "load-from-webhooks-api", "a", false, "Load webhook endpoint configuration from the webhooks API")
 	lc.cmd.Flags().BoolVarP(&lc.skipVerify, "skip-verify", "", false, "Skip certificate verification when forwarding to HTTPS endpoints")
 	lc.cmd.Flags().BoolVar(&lc.onlyPrintSecret, "print-secret", false, "Only print the webhook signing secret and exit")
+	lc.cmd.Flags().IntVar(&lc.retries, "retries", 0, "Number of times to retry forwarding an event when the endpoint can't be reached or returns a 5xx status")
+	lc.cmd.Flags().DurationVar(&lc.retryBackoff, "retry-backoff", time.Second, "Delay before the first retry, doubled after each subsequent attempt")
+	lc.cmd.Flags().DurationVar(&lc.timeout, "timeout", 30*time.Second, "Maximum time to wait for a response when forwarding an event")
+	lc.cmd.Flags().DurationVar(&lc.shutdownTimeout, "shutdown-timeout", 10*time.Second, "Maximum time to wait for events being forwarded to complete when exiting")
+	lc.cmd.Flags().BoolVar(&lc.backfill, "backfill", false, "After a disconnection, fetch the events created in the meantime from the API and forward them")
+	lc.cmd.Flags().BoolVar(&lc.reconnectForever, "reconnect-forever", false, "Keep renewing the session when it expires or cannot be renewed, waiting up to a minute between attempts, instead of exiting after 3 failed attempts")
+	lc.cmd.Flags().IntVar(&lc.maxConcurrency, "max-concurrency", proxy.DefaultMaxConcurrentDeliveries, "Maximum number of events forwarded at the same time")
+	lc.cmd.Flags().BoolVar(&lc.ordered, "ordered", false, "Forward events about the same object one at a time, in the order they were received")
+	lc.cmd.Flags().StringVar(&lc.signingSecret, "signing-secret", "", "Re-sign forwarded events with this webhook signing secret (whsec_...) instead of the session's secret")
+	lc.cmd.Flags().StringVar(&lc.routesFile, "routes", "", "A TOML file describing additional endpoints to forward events to, with their own headers, events and settings")
+	lc.cmd.Flags().BoolVar(&lc.showResponses, "show-responses", false, "Print the latency of each forwarded event and the body of non-2xx responses")
+	lc.cmd.Flags().IntVar(&lc.localPort, "local-port", 0, "Also accept event JSON POSTed to this port on localhost, and forward it like the events sent by Stripe")
+	lc.cmd.Flags().BoolVar(&lc.localOnly, "local-only", false, "Only accept events on --local-port, without connecting to Stripe")
+	lc.cmd.Flags().BoolVar(&lc.tui, "tui", false, "Display events and endpoint responses in an interactive, full-screen interface")
+	lc.cmd.Flags().StringArrayVar(&lc.filters, "filter", []string{}, "Only forward events whose payload matches this condition, e.g. \"data.object.amount > 1000\" or \"data.object.metadata.order_id exists\" (can be repeated)")
+	lc.cmd.Flags().StringVar(&lc.transformsFile, "transforms", "", "A TOML file of rules to modify events with before forwarding them (set or delete fields, rewrite strings, add headers)")
+	lc.cmd.Flags().StringVar(&lc.journalFile, "journal", "", "Append received events and endpoint responses to this file, for use with `stripe listen replay`")
 
 	// Hidden configuration flags, useful for dev/debugging
 	lc.cmd.Flags().StringVar(&lc.apiBaseURL, "api-base", "", "Sets the API base URL")
@@ -81,26 +129,58 @@ Stripe account.`,
 // Normally, this function would be listed alphabetically with the others declared in this file,
 // but since it's acting as the core functionality for the cmd above, I'm keeping it close.
 func (lc *listenCmd) runListenCmd(cmd *cobra.Command, args []string) error {
-	deviceName, err := Config.Profile.GetDeviceName()
-	if err != nil {
-		return err
+	if lc.localOnly {
+		if lc.localPort == 0 {
+			return errors.New("--local-only requires a port to accept events on with --local-port")
+		}
+
+		if lc.loadFromWebhooksAPI || lc.onlyPrintSecret {
+			return errors.New("--local-only cannot be used with --load-from-webhooks-api or --print-secret")
+		}
+	}
+
+	if lc.localPort < 0 || lc.localPort > 65535 {
+		return errors.New("--local-port must be a valid port number")
+	}
+
+	var deviceName, key string
+	var err error
+
+	// Events received on the local ingress only don't require a Stripe account
+	if !lc.localOnly {
+		deviceName, err = Config.Profile.GetDeviceName()
+		if err != nil {
+			return err
+		}
+
+		key, err = Config.Profile.GetAPIKey(lc.livemode)
+		if err != nil {
+			return err
+		}
 	}
 
 	endpointRoutes := make([]proxy.EndpointRoute, 0)
 
-	key, err := Config.Profile.GetAPIKey(lc.livemode)
-	if err != nil {
-		return err
+	if lc.tui && lc.printJSON {
+		return errors.New("--tui and --print-json cannot be used together")
 	}
 
-	if !lc.printJSON && !lc.onlyPrintSecret {
+	switch lc.format {
+	case "":
+	case "jsonl":
+		if lc.tui || lc.printJSON {
+			return errors.New("--format jsonl cannot be used with --tui or --print-json")
+		}
+	default:
+		return fmt.Errorf("Unsupported output format %q, the only supported format is jsonl", lc.format)
+	}
+
+	if !lc.printJSON && !lc.onlyPrintSecret && !lc.tui && !lc.localOnly && lc.format == "" {
 		version.CheckLatestVersion()
 	}
 
-	for _, event := range lc.events {
-		if _, found := validEvents[event]; !found {
-			fmt.Printf("Warning: You're attempting to listen for \"%s\", which isn't a valid event\n", event)
-		}
+	for _, event := range unmatchedEventPatterns(lc.events) {
+		fmt.Fprintf(os.Stderr, "Warning: You're attempting to listen for \"%s\", which doesn't match any valid event\n", event)
 	}
 
 	if len(lc.events) == 0 {
@@ -143,6 +223,10 @@ func (lc *listenCmd) runListenCmd(cmd *cobra.Command, args []string) error {
 			return errors.New("--forward-connect-to cannot be a relative path when loading webhook endpoints from the API")
 		}
 
+		if proxy.IsSinkURL(lc.forwardURL) || proxy.IsSinkURL(lc.forwardConnectURL) {
+			return errors.New("exec:, file: and unix: destinations cannot be used when loading webhook endpoints from the API")
+		}
+
 		endpoints := lc.getEndpointsFromAPI(key)
 		if len(endpoints.Data) == 0 {
 			return errors.New("You have not defined any webhook endpoints on your account. Go to the Stripe Dashboard to add some: https://dashboard.stripe.com/test/webhooks")
@@ -153,18 +237,129 @@ func (lc *listenCmd) runListenCmd(cmd *cobra.Command, args []string) error {
 		return errors.New("--load-from-webhooks-api requires a location to forward to with --forward-to")
 	}
 
-	p := proxy.New(&proxy.Config{
-		DeviceName:          deviceName,
-		Key:                 key,
-		EndpointRoutes:      endpointRoutes,
-		APIBaseURL:          lc.apiBaseURL,
-		WebSocketFeature:    webhooksWebSocketFeature,
-		PrintJSON:           lc.printJSON,
-		UseLatestAPIVersion: lc.latestAPIVersion,
-		SkipVerify:          lc.skipVerify,
-		Log:                 log.StandardLogger(),
-		NoWSS:               lc.noWSS,
-	}, lc.events)
+	if lc.retries < 0 {
+		return errors.New("--retries cannot be negative")
+	}
+
+	retry := proxy.RetryPolicy{
+		MaxAttempts:        lc.retries + 1,
+		InitialBackoff:     lc.retryBackoff,
+		RetryOnServerError: true,
+	}
+
+	if err := validators.CallNonEmpty(validators.WebhookSigningSecret, lc.signingSecret); err != nil {
+		return err
+	}
+
+	if lc.maxConcurrency < 1 {
+		return errors.New("--max-concurrency must be at least 1")
+	}
+
+	for i := range endpointRoutes {
+		endpointRoutes[i].Retry = retry
+		endpointRoutes[i].SigningSecret = lc.signingSecret
+		endpointRoutes[i].Timeout = lc.timeout
+	}
+
+	if lc.routesFile != "" {
+		if lc.loadFromWebhooksAPI {
+			return errors.New("--routes cannot be used with --load-from-webhooks-api")
+		}
+
+		fileRoutes, err := loadRoutesFile(fs, lc.routesFile, retry)
+		if err != nil {
+			return err
+		}
+
+		for i := range fileRoutes {
+			if fileRoutes[i].SigningSecret == "" {
+				fileRoutes[i].SigningSecret = lc.signingSecret
+			}
+
+			if fileRoutes[i].Timeout == 0 {
+				fileRoutes[i].Timeout = lc.timeout
+			}
+		}
+
+		for _, route := range fileRoutes {
+			for _, event := range unmatchedEventPatterns(route.EventTypes) {
+				fmt.Fprintf(os.Stderr, "Warning: Route %s is attempting to listen for \"%s\", which doesn't match any valid event\n", route.URL, event)
+			}
+		}
+
+		endpointRoutes = append(endpointRoutes, fileRoutes...)
+	}
+
+	filters := make([]*proxy.PayloadFilter, 0, len(lc.filters))
+
+	for _, expr := range lc.filters {
+		filter, err := proxy.ParsePayloadFilter(expr)
+		if err != nil {
+			return err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	var transforms []proxy.Transform
+
+	if lc.transformsFile != "" {
+		transform, err := loadTransformsFile(fs, lc.transformsFile)
+		if err != nil {
+			return err
+		}
+
+		transforms = append(transforms, transform)
+	}
+
+	var journal *proxy.Journal
+
+	if lc.journalFile != "" && !lc.onlyPrintSecret {
+		journal, err = proxy.OpenJournal(lc.journalFile)
+		if err != nil {
+			return err
+		}
+		defer journal.Close()
+	}
+
+	proxyCfg := &proxy.Config{
+		DeviceName:              deviceName,
+		Key:                     key,
+		EndpointRoutes:          endpointRoutes,
+		APIBaseURL:              lc.apiBaseURL,
+		WebSocketFeature:        webhooksWebSocketFeature,
+		PrintJSON:               lc.printJSON,
+		ShowResponseDetails:     lc.showResponses,
+		LocalAddr:               localAddr(lc.localPort),
+		LocalOnly:               lc.localOnly,
+		ShutdownTimeout:         lc.shutdownTimeout,
+		UnlimitedSessionRenewal: lc.reconnectForever,
+		Backfill:                lc.backfill,
+		Filters:                 filters,
+		Transforms:              transforms,
+		UseLatestAPIVersion:     lc.latestAPIVersion,
+		SkipVerify:              lc.skipVerify,
+		Log:                     log.StandardLogger(),
+		NoWSS:                   lc.noWSS,
+		Journal:                 journal,
+
+		MaxConcurrentDeliveries: lc.maxConcurrency,
+		OrderedDelivery:         lc.ordered,
+	}
+
+	if lc.tui && !lc.onlyPrintSecret {
+		return lc.runListenUI(proxyCfg, endpointRoutes)
+	}
+
+	if lc.format == "jsonl" {
+		proxyCfg.Observer = proxy.NewJSONLObserver(os.Stdout)
+		proxyCfg.Out = ioutil.Discard
+	}
+
+	p, err := proxy.New(proxyCfg, lc.events)
+	if err != nil {
+		return err
+	}
 
 	if lc.onlyPrintSecret {
 		secret, err := p.GetSessionSecret(context.Background())
@@ -175,7 +370,13 @@ func (lc *listenCmd) runListenCmd(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	err = p.Run(context.Background())
+	ctx := withSIGTERMCancel(context.Background(), func() {
+		log.WithFields(log.Fields{
+			"prefix": "cmd.listenCmd.runListenCmd",
+		}).Debug("Ctrl+C received, cleaning up...")
+	})
+
+	err = p.Run(ctx)
 	if err != nil {
 		return err
 	}
@@ -222,9 +423,36 @@ func buildEndpointRoutes(endpoints requests.WebhookEndpointList, forwardURL, for
 	return endpointRoutes
 }
 
+// unmatchedEventPatterns returns the event patterns that don't match any
+// known event type.
+func unmatchedEventPatterns(patterns []string) []string {
+	unmatched := make([]string, 0)
+
+	for _, pattern := range patterns {
+		found := false
+
+		for event := range validEvents {
+			if proxy.EventPatternMatches(pattern, event) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	return unmatched
+}
+
 // parseURL parses the potentially incomplete URL provided in the configuration
 // and returns a full URL
 func parseURL(url string) string {
+	if proxy.IsSinkURL(url) {
+		return url
+	}
+
 	_, err := strconv.Atoi(url)
 	if err == nil {
 		// If the input is just a number, assume it's a port number
@@ -252,3 +480,109 @@ func buildForwardURL(forwardURL string, destination *url.URL) string {
 
 	return fmt.Sprintf("%s://%s%s", f.Scheme, f.Host, destination.Path)
 }
+
+// runListenUI runs the proxy with the interactive interface. Log messages are
+// displayed in the interface's status bar rather than printed.
+func (lc *listenCmd) runListenUI(proxyCfg *proxy.Config, endpointRoutes []proxy.EndpointRoute) error {
+	var p *proxy.Proxy
+
+	ui := listenui.New(&listenui.Config{
+		Endpoints: routeURLs(endpointRoutes),
+		Resend: func(evt *proxy.ReceivedEvent) {
+			var apiVersion *string
+			if evt.APIVersion != "" {
+				apiVersion = &evt.APIVersion
+			}
+
+			// Resent events are signed again by the proxy. Failed deliveries
+			// are shown in the interface.
+			p.Replay([]*proxy.JournalEntry{{ //nolint:errcheck
+				ReceivedAt:            evt.Time,
+				WebhookID:             evt.WebhookID,
+				WebhookConversationID: evt.WebhookConversationID,
+				APIVersion:            apiVersion,
+				EventPayload:          evt.Payload,
+				HTTPHeaders:           evt.Headers,
+				EventID:               evt.EventID,
+				EventType:             evt.EventType,
+			}})
+		},
+	})
+
+	if err := ui.Open(); err != nil {
+		return err
+	}
+
+	// Restore the terminal if the program exits with a fatal error
+	log.RegisterExitHandler(ui.Abort)
+
+	proxyCfg.Log.SetOutput(ui)
+	proxyCfg.Out = ioutil.Discard
+	proxyCfg.Observer = ui
+
+	p, err := proxy.New(proxyCfg, lc.events)
+	if err != nil {
+		ui.Close()
+		return err
+	}
+
+	// In raw mode, Ctrl+C is handled by the interface rather than sent as a
+	// signal
+	ctx, cancel := context.WithCancel(withSIGTERMCancel(context.Background(), func() {}))
+
+	go func() {
+		ui.Run()
+		cancel()
+	}()
+
+	err = p.Run(ctx)
+
+	ui.Close()
+
+	return err
+}
+
+// routeURLs returns the distinct URLs of the given routes, in order.
+func routeURLs(endpointRoutes []proxy.EndpointRoute) []string {
+	urls := make([]string, 0, len(endpointRoutes))
+	seen := make(map[string]bool)
+
+	for _, route := range endpointRoutes {
+		if !seen[route.URL] {
+			seen[route.URL] = true
+			urls = append(urls, route.URL)
+		}
+	}
+
+	return urls
+}
+
+// localAddr returns the address of the local ingress for the given port, or
+// an empty string if it is disabled.
+func localAddr(port int) string {
+	if port == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("localhost:%d", port)
+}
+
+// withSIGTERMCancel returns a context that is canceled when Ctrl+C is
+// pressed or SIGTERM is received. A second Ctrl+C terminates the process
+// immediately.
+func withSIGTERMCancel(ctx context.Context, onCancel func()) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+
+	interruptCh := make(chan os.Signal, 1)
+	signal.Notify(interruptCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-interruptCh
+		onCancel()
+		cancel()
+
+		signal.Stop(interruptCh)
+	}()
+
+	return ctx
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/stripe/stripe-cli/pkg/proxy"
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+type listenReplayCmd struct {
+	cmd *cobra.Command
+
+	journalFile           string
+	forwardURL            string
+	forwardHeaders        []string
+	forwardConnectHeaders []string
+	forwardConnectURL     string
+	events                []string
+	last                  int
+	skipVerify            bool
+	signingSecret         string
+	transformsFile        string
+}
+
+func newListenReplayCmd() *listenReplayCmd {
+	lrc := &listenReplayCmd{}
+
+	lrc.cmd = &cobra.Command{
+		Use:   "replay [event ids...]",
+		Short: "Replay journaled webhook events to a local endpoint",
+		Long: `The replay command re-sends webhook events that were recorded with
+"stripe listen --journal" to your local endpoint, without going through Stripe.
+Events can be selected by ID, by type, or by how recently they were received.
+
+The original Stripe-Signature headers have likely expired, so events are signed
+again with a fresh timestamp, using the same secret as "stripe listen" unless
+--signing-secret is passed.`,
+		Example: `stripe listen replay --journal events.jsonl --forward-to localhost:3000/events evt_123
+  stripe listen replay --journal events.jsonl --forward-to localhost:3000/events \
+    --events invoice.payment_failed --last 1`,
+		RunE: lrc.runListenReplayCmd,
+	}
+
+	lrc.cmd.Flags().StringVar(&lrc.journalFile, "journal", "", "The journal file written by `stripe listen --journal`")
+	lrc.cmd.Flags().StringSliceVar(&lrc.forwardConnectHeaders, "connect-headers", []string{}, "A comma-separated list of custom headers to forward for Connect")
+	lrc.cmd.Flags().StringSliceVarP(&lrc.events, "events", "e", []string{"*"}, "A comma-separated list of specific events to replay")
+	lrc.cmd.Flags().StringVarP(&lrc.forwardURL, "forward-to", "f", "", "The URL to forward webhook events to")
+	lrc.cmd.Flags().StringSliceVarP(&lrc.forwardHeaders, "headers", "H", []string{}, "A comma-separated list of custom headers to forward")
+	lrc.cmd.Flags().StringVarP(&lrc.forwardConnectURL, "forward-connect-to", "c", "", "The URL to forward Connect webhook events to (default: same as normal events)")
+	lrc.cmd.Flags().IntVar(&lrc.last, "last", 0, "Only replay the N most recently received matching events")
+	lrc.cmd.Flags().StringVar(&lrc.signingSecret, "signing-secret", "", "Re-sign replayed events with this webhook signing secret (whsec_...) so they pass signature verification")
+	lrc.cmd.Flags().StringVar(&lrc.transformsFile, "transforms", "", "A TOML file of rules to modify events with before forwarding them")
+	lrc.cmd.Flags().BoolVarP(&lrc.skipVerify, "skip-verify", "", false, "Skip certificate verification when forwarding to HTTPS endpoints")
+
+	return lrc
+}
+
+func (lrc *listenReplayCmd) runListenReplayCmd(cmd *cobra.Command, args []string) error {
+	if lrc.journalFile == "" {
+		return errors.New("--journal is required")
+	}
+
+	if lrc.forwardURL == "" {
+		return errors.New("--forward-to is required")
+	}
+
+	if lrc.last < 0 {
+		return errors.New("--last cannot be negative")
+	}
+
+	if err := validators.CallNonEmpty(validators.WebhookSigningSecret, lrc.signingSecret); err != nil {
+		return err
+	}
+
+	if len(lrc.events) == 0 {
+		lrc.events = []string{"*"}
+	}
+
+	if len(lrc.forwardConnectURL) == 0 {
+		lrc.forwardConnectURL = lrc.forwardURL
+	}
+
+	if len(lrc.forwardConnectHeaders) == 0 {
+		lrc.forwardConnectHeaders = lrc.forwardHeaders
+	}
+
+	var transforms []proxy.Transform
+
+	if lrc.transformsFile != "" {
+		transform, err := loadTransformsFile(fs, lrc.transformsFile)
+		if err != nil {
+			return err
+		}
+
+		transforms = append(transforms, transform)
+	}
+
+	entries, err := proxy.ReadJournal(lrc.journalFile)
+	if err != nil {
+		return err
+	}
+
+	entries = selectJournalEntries(entries, args, lrc.events, lrc.last)
+	if len(entries) == 0 {
+		return errors.New("No journaled events matched the given criteria")
+	}
+
+	// Without --signing-secret, events are signed with the secret of the
+	// listen sessions, which requires being logged in
+	var deviceName, key string
+
+	if lrc.signingSecret == "" {
+		deviceName, err = Config.Profile.GetDeviceName()
+		if err != nil {
+			return err
+		}
+
+		key, err = Config.Profile.GetAPIKey(false)
+		if err != nil {
+			return err
+		}
+	}
+
+	p, err := proxy.New(&proxy.Config{
+		DeviceName: deviceName,
+		Key:        key,
+		EndpointRoutes: []proxy.EndpointRoute{
+			{
+				URL:            parseURL(lrc.forwardURL),
+				ForwardHeaders: lrc.forwardHeaders,
+				Connect:        false,
+				EventTypes:     lrc.events,
+				SigningSecret:  lrc.signingSecret,
+			},
+			{
+				URL:            parseURL(lrc.forwardConnectURL),
+				ForwardHeaders: lrc.forwardConnectHeaders,
+				Connect:        true,
+				EventTypes:     lrc.events,
+				SigningSecret:  lrc.signingSecret,
+			},
+		},
+		SkipVerify:       lrc.skipVerify,
+		Transforms:       transforms,
+		Log:              log.StandardLogger(),
+		WebSocketFeature: webhooksWebSocketFeature,
+	}, lrc.events)
+	if err != nil {
+		return err
+	}
+
+	if lrc.signingSecret == "" {
+		if _, err := p.GetSessionSecret(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not get the webhook signing secret of stripe listen (%v). Replayed events keep their original signatures, which may have expired; pass --signing-secret to sign them again\n", err)
+		}
+	}
+
+	fmt.Printf("Replaying %d event(s) from %s\n", len(entries), lrc.journalFile)
+
+	return p.Replay(entries)
+}
+
+// selectJournalEntries keeps the entries whose event ID is in ids (or all of
+// them if ids is empty) and whose type is in events, then only the last n of
+// those if n is positive. Events journaled with several API versions are only
+// replayed with one: the account's default version if it was journaled.
+func selectJournalEntries(entries []*proxy.JournalEntry, ids []string, events []string, n int) []*proxy.JournalEntry {
+	idsMap := make(map[string]bool)
+	for _, id := range ids {
+		idsMap[id] = true
+	}
+
+	versions := make(map[string]string)
+
+	for _, entry := range entries {
+		version, ok := versions[entry.EventID]
+		if !ok || (version != "" && entry.APIVersion == nil) {
+			versions[entry.EventID] = journalEntryVersion(entry)
+		}
+	}
+
+	selected := make([]*proxy.JournalEntry, 0)
+
+	for _, entry := range entries {
+		if len(idsMap) > 0 && !idsMap[entry.EventID] {
+			continue
+		}
+
+		if journalEntryVersion(entry) != versions[entry.EventID] {
+			continue
+		}
+
+		if !proxy.EventTypeMatches(events, entry.EventType) {
+			continue
+		}
+
+		selected = append(selected, entry)
+	}
+
+	if n > 0 && len(selected) > n {
+		selected = selected[len(selected)-n:]
+	}
+
+	return selected
+}
+
+// journalEntryVersion returns the API version requested for the delivery of
+// an entry, or "" for the account's default version.
+func journalEntryVersion(entry *proxy.JournalEntry) string {
+	if entry.APIVersion == nil {
+		return ""
+	}
+
+	return *entry.APIVersion
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/proxy"
+)
+
+func TestSelectJournalEntries(t *testing.T) {
+	entries := []*proxy.JournalEntry{
+		{EventID: "evt_1", EventType: "invoice.payment_failed"},
+		{EventID: "evt_2", EventType: "customer.created"},
+		{EventID: "evt_3", EventType: "invoice.payment_failed"},
+		{EventID: "evt_1", EventType: "invoice.payment_failed"},
+	}
+
+	require.Equal(t, 4, len(selectJournalEntries(entries, []string{}, []string{"*"}, 0)))
+
+	selected := selectJournalEntries(entries, []string{"evt_1"}, []string{"*"}, 0)
+	require.Equal(t, 2, len(selected))
+
+	selected = selectJournalEntries(entries, []string{}, []string{"invoice.payment_failed"}, 2)
+	require.Equal(t, 2, len(selected))
+	require.Equal(t, "evt_3", selected[0].EventID)
+	require.Equal(t, "evt_1", selected[1].EventID)
+
+	selected = selectJournalEntries(entries, []string{"evt_2"}, []string{"invoice.payment_failed"}, 0)
+	require.Equal(t, 0, len(selected))
+}
+
+func TestSelectJournalEntriesRenderings(t *testing.T) {
+	latest := "2020-08-27"
+
+	entries := []*proxy.JournalEntry{
+		{EventID: "evt_1", EventType: "customer.created", APIVersion: &latest},
+		{EventID: "evt_1", EventType: "customer.created"},
+		{EventID: "evt_2", EventType: "customer.created", APIVersion: &latest},
+		{EventID: "evt_1", EventType: "customer.created"},
+	}
+
+	// Redeliveries are kept, but only with the account's default version
+	selected := selectJournalEntries(entries, []string{}, []string{"*"}, 0)
+	require.Equal(t, []*proxy.JournalEntry{entries[1], entries[2], entries[3]}, selected)
+}
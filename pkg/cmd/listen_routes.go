@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/afero"
+
+	"github.com/stripe/stripe-cli/pkg/proxy"
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+// routesFile is the structure of the file passed to `stripe listen --routes`.
+// For example:
+//
+//	[[routes]]
+//	url = "localhost:4000/billing/webhooks"
+//	events = ["invoice.payment_failed", "invoice.paid"]
+//	headers = ["X-Service: billing"]
+//
+//	[[routes]]
+//	url = "https://localhost:5000/webhooks"
+//	events = ["checkout.session.completed"]
+//	connect = true
+//	skip_verify = true
+//	retries = 3
+//	signing_secret = "whsec_..."
+//	timeout = "2m"
+//
+//	[[routes]]
+//	url = "https://localhost:6000/webhooks"
+//	ca_file = "certs/dev-ca.pem"
+//	cert_file = "certs/client.pem"
+//	key_file = "certs/client-key.pem"
+//	server_name = "payments.dev.internal"
+//
+//	[[routes]]
+//	url = "localhost:4001/billing/webhooks"
+//	events = ["invoice.paid"]
+//	api_version = "2020-08-27"
+//
+// Routes with an api_version only receive the events rendered with that
+// version, which must be the account's default or the latest one.
+// Relative certificate paths are resolved from the directory of the routes
+// file.
+type routesFile struct {
+	Routes []routeConfig `toml:"routes"`
+}
+
+type routeConfig struct {
+	URL           string   `toml:"url"`
+	Headers       []string `toml:"headers"`
+	Events        []string `toml:"events"`
+	Connect       bool     `toml:"connect"`
+	SkipVerify    bool     `toml:"skip_verify"`
+	Retries       *int     `toml:"retries"`
+	RetryBackoff  string   `toml:"retry_backoff"`
+	SigningSecret string   `toml:"signing_secret"`
+	Timeout       string   `toml:"timeout"`
+	CAFile        string   `toml:"ca_file"`
+	CertFile      string   `toml:"cert_file"`
+	KeyFile       string   `toml:"key_file"`
+	ServerName    string   `toml:"server_name"`
+	APIVersion    string   `toml:"api_version"`
+}
+
+// loadRoutesFile reads the routes file at path and builds the corresponding
+// endpoint routes. Retry settings that are not specified on a route fall back
+// to defaultRetry.
+func loadRoutesFile(fs afero.Fs, path string, defaultRetry proxy.RetryPolicy) ([]proxy.EndpointRoute, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rf routesFile
+	if _, err := toml.Decode(string(data), &rf); err != nil {
+		return nil, fmt.Errorf("Could not parse routes file %s: %v", path, err)
+	}
+
+	if len(rf.Routes) == 0 {
+		return nil, fmt.Errorf("No routes are defined in %s", path)
+	}
+
+	endpointRoutes := make([]proxy.EndpointRoute, 0, len(rf.Routes))
+
+	for i, route := range rf.Routes {
+		if route.URL == "" {
+			return nil, fmt.Errorf("Route #%d in %s is missing a url", i+1, path)
+		}
+
+		if len(route.Events) == 0 {
+			route.Events = []string{"*"}
+		}
+
+		for _, header := range route.Headers {
+			if !strings.Contains(header, ":") {
+				return nil, fmt.Errorf("Invalid header %q in route #%d of %s", header, i+1, path)
+			}
+		}
+
+		retry := defaultRetry
+
+		if route.Retries != nil {
+			if *route.Retries < 0 {
+				return nil, fmt.Errorf("Invalid retries for route #%d in %s: cannot be negative", i+1, path)
+			}
+
+			retry.MaxAttempts = *route.Retries + 1
+		}
+
+		if route.RetryBackoff != "" {
+			retry.InitialBackoff, err = time.ParseDuration(route.RetryBackoff)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid retry_backoff for route #%d in %s: %v", i+1, path, err)
+			}
+		}
+
+		var timeout time.Duration
+		if route.Timeout != "" {
+			timeout, err = time.ParseDuration(route.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid timeout for route #%d in %s: %v", i+1, path, err)
+			}
+		}
+
+		if err := validators.CallNonEmpty(validators.WebhookSigningSecret, route.SigningSecret); err != nil {
+			return nil, fmt.Errorf("Invalid signing_secret for route #%d in %s: %v", i+1, path, err)
+		}
+
+		endpointRoutes = append(endpointRoutes, proxy.EndpointRoute{
+			URL:            parseURL(route.URL),
+			ForwardHeaders: route.Headers,
+			Connect:        route.Connect,
+			EventTypes:     route.Events,
+			Retry:          retry,
+			SkipVerify:     route.SkipVerify,
+			SigningSecret:  route.SigningSecret,
+			Timeout:        timeout,
+			APIVersion:     route.APIVersion,
+			TLS: proxy.TLSConfig{
+				CAFile:     resolveRoutePath(path, route.CAFile),
+				CertFile:   resolveRoutePath(path, route.CertFile),
+				KeyFile:    resolveRoutePath(path, route.KeyFile),
+				ServerName: route.ServerName,
+			},
+		})
+	}
+
+	return endpointRoutes, nil
+}
+
+// resolveRoutePath resolves a path found in the routes file at routesPath
+// relative to the file's directory.
+func resolveRoutePath(routesPath, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(routesPath), path)
+}
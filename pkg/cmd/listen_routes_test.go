@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/proxy"
+)
+
+func TestLoadRoutesFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	data := `
+[[routes]]
+url = "localhost:4000/billing"
+events = ["invoice.paid", "invoice.payment_failed"]
+headers = ["X-Service: billing"]
+
+[[routes]]
+url = "https://localhost:5000/fulfillment"
+connect = true
+skip_verify = true
+retries = 3
+retry_backoff = "250ms"
+timeout = "2m"
+api_version = "2020-08-27"
+`
+	afero.WriteFile(fs, "routes.toml", []byte(data), 0644)
+
+	defaultRetry := proxy.RetryPolicy{MaxAttempts: 1, InitialBackoff: time.Second, RetryOnServerError: true}
+
+	routes, err := loadRoutesFile(fs, "routes.toml", defaultRetry)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(routes))
+
+	require.Equal(t, "http://localhost:4000/billing", routes[0].URL)
+	require.Equal(t, []string{"invoice.paid", "invoice.payment_failed"}, routes[0].EventTypes)
+	require.Equal(t, []string{"X-Service: billing"}, routes[0].ForwardHeaders)
+	require.False(t, routes[0].Connect)
+	require.False(t, routes[0].SkipVerify)
+	require.Equal(t, defaultRetry, routes[0].Retry)
+
+	require.Equal(t, "https://localhost:5000/fulfillment", routes[1].URL)
+	require.Equal(t, []string{"*"}, routes[1].EventTypes)
+	require.True(t, routes[1].Connect)
+	require.True(t, routes[1].SkipVerify)
+	require.Equal(t, 4, routes[1].Retry.MaxAttempts)
+	require.Equal(t, 250*time.Millisecond, routes[1].Retry.InitialBackoff)
+	require.True(t, routes[1].Retry.RetryOnServerError)
+	require.Equal(t, 2*time.Minute, routes[1].Timeout)
+	require.Equal(t, time.Duration(0), routes[0].Timeout)
+	require.Equal(t, "", routes[0].APIVersion)
+	require.Equal(t, "2020-08-27", routes[1].APIVersion)
+}
+
+func TestLoadRoutesFileErrors(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	_, err := loadRoutesFile(fs, "missing.toml", proxy.RetryPolicy{})
+	require.Error(t, err)
+
+	afero.WriteFile(fs, "empty.toml", []byte(""), 0644)
+	_, err = loadRoutesFile(fs, "empty.toml", proxy.RetryPolicy{})
+	require.EqualError(t, err, "No routes are defined in empty.toml")
+
+	afero.WriteFile(fs, "nourl.toml", []byte("[[routes]]\nevents = [\"*\"]\n"), 0644)
+	_, err = loadRoutesFile(fs, "nourl.toml", proxy.RetryPolicy{})
+	require.EqualError(t, err, "Route #1 in nourl.toml is missing a url")
+
+	afero.WriteFile(fs, "header.toml", []byte("[[routes]]\nurl = \"localhost:3000\"\nheaders = [\"X-Tenant acme\"]\n"), 0644)
+	_, err = loadRoutesFile(fs, "header.toml", proxy.RetryPolicy{})
+	require.EqualError(t, err, `Invalid header "X-Tenant acme" in route #1 of header.toml`)
+}
+
+func TestLoadRoutesFileSigningSecret(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	afero.WriteFile(fs, "routes.toml", []byte("[[routes]]\nurl = \"3000\"\nsigning_secret = \"whsec_123\"\n"), 0644)
+	routes, err := loadRoutesFile(fs, "routes.toml", proxy.RetryPolicy{})
+	require.NoError(t, err)
+	require.Equal(t, "whsec_123", routes[0].SigningSecret)
+
+	afero.WriteFile(fs, "invalid.toml", []byte("[[routes]]\nurl = \"3000\"\nsigning_secret = \"sk_test_123\"\n"), 0644)
+	_, err = loadRoutesFile(fs, "invalid.toml", proxy.RetryPolicy{})
+	require.Error(t, err)
+}
+
+func TestLoadRoutesFileTLS(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	data := `
+[[routes]]
+url = "https://localhost:6000/webhooks"
+ca_file = "certs/ca.pem"
+cert_file = "/etc/certs/client.pem"
+key_file = "/etc/certs/client-key.pem"
+server_name = "payments.dev.internal"
+`
+	afero.WriteFile(fs, "config/routes.toml", []byte(data), 0644)
+
+	routes, err := loadRoutesFile(fs, "config/routes.toml", proxy.RetryPolicy{})
+	require.NoError(t, err)
+	require.Equal(t, proxy.TLSConfig{
+		CAFile:     "config/certs/ca.pem",
+		CertFile:   "/etc/certs/client.pem",
+		KeyFile:    "/etc/certs/client-key.pem",
+		ServerName: "payments.dev.internal",
+	}, routes[0].TLS)
+}
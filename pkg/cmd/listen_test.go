@@ -66,3 +66,8 @@ func TestBuildForwardURL(t *testing.T) {
 	require.Equal(t, "https://localhost/bar/", buildForwardURL("https://localhost/", f))
 	require.Equal(t, "http://localhost:8000/bar/", buildForwardURL("http://localhost:8000", f))
 }
+
+func TestUnmatchedEventPatterns(t *testing.T) {
+	require.Equal(t, []string{}, unmatchedEventPatterns([]string{"*", "charge.succeeded", "invoice.*", "!charge.updated"}))
+	require.Equal(t, []string{"charge.bogus", "bogus.*"}, unmatchedEventPatterns([]string{"charge.bogus", "customer.subscription.*", "bogus.*"}))
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/afero"
+
+	"github.com/stripe/stripe-cli/pkg/proxy"
+)
+
+// transformsFile is the structure of the file passed to
+// `stripe listen --transforms`. Rules are applied in order. For example:
+//
+//	# Point URLs in the payload to the local server
+//	[[rules]]
+//	replace = "https://shop.example.com"
+//	with = "http://localhost:3000"
+//	path = "data.object"
+//
+//	# Inject a tenant header derived from the event's account
+//	[[rules]]
+//	header = "X-Tenant"
+//	value = "${account}"
+//
+//	[[rules]]
+//	set = "data.object.metadata.env"
+//	value = "dev"
+//
+//	[[rules]]
+//	delete = "data.object.customer_details"
+type transformsFile struct {
+	Rules []transformRuleConfig `toml:"rules"`
+}
+
+type transformRuleConfig struct {
+	Set     string      `toml:"set"`
+	Delete  string      `toml:"delete"`
+	Header  string      `toml:"header"`
+	Replace string      `toml:"replace"`
+	With    string      `toml:"with"`
+	Path    string      `toml:"path"`
+	Value   interface{} `toml:"value"`
+}
+
+// loadTransformsFile reads the transform rules in the file at path.
+func loadTransformsFile(fs afero.Fs, path string) (proxy.Transform, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tf transformsFile
+	if _, err := toml.Decode(string(data), &tf); err != nil {
+		return nil, fmt.Errorf("Could not parse transforms file %s: %v", path, err)
+	}
+
+	if len(tf.Rules) == 0 {
+		return nil, fmt.Errorf("No rules are defined in %s", path)
+	}
+
+	rules := make([]proxy.TransformRule, 0, len(tf.Rules))
+
+	for i, rule := range tf.Rules {
+		// Non-string values (numbers, booleans, tables...) are passed as
+		// JSON, so that they are inserted with the same type
+		value := ""
+		jsonValue := false
+
+		switch v := rule.Value.(type) {
+		case nil:
+		case string:
+			value = v
+		default:
+			jsonValue = true
+
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value for rule #%d in %s: %v", i+1, path, err)
+			}
+
+			value = string(encoded)
+		}
+
+		rules = append(rules, proxy.TransformRule{
+			Set:       rule.Set,
+			Delete:    rule.Delete,
+			Header:    rule.Header,
+			Replace:   rule.Replace,
+			With:      rule.With,
+			Path:      rule.Path,
+			Value:     value,
+			JSONValue: jsonValue,
+		})
+	}
+
+	transform, err := proxy.NewRuleTransform(rules)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid transforms file %s: %v", path, err)
+	}
+
+	return transform, nil
+}
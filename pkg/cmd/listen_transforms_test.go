@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/proxy"
+)
+
+func TestLoadTransformsFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	data := `
+[[rules]]
+header = "X-Tenant"
+value = "${account}"
+
+[[rules]]
+set = "data.object.amount"
+value = 2000
+
+[[rules]]
+delete = "data.object.customer_details"
+`
+	afero.WriteFile(fs, "transforms.toml", []byte(data), 0644)
+
+	transform, err := loadTransformsFile(fs, "transforms.toml")
+	require.NoError(t, err)
+
+	evt := &proxy.ForwardedEvent{
+		Payload: `{"account":"acct_123","data":{"object":{"amount":1000,"customer_details":{}}}}`,
+		Headers: map[string]string{},
+	}
+
+	require.NoError(t, transform.Apply(evt))
+	require.Equal(t, "acct_123", evt.Headers["X-Tenant"])
+	require.JSONEq(t, `{"account":"acct_123","data":{"object":{"amount":2000}}}`, evt.Payload)
+}
+
+func TestLoadTransformsFileInvalid(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	afero.WriteFile(fs, "empty.toml", []byte(""), 0644)
+	_, err := loadTransformsFile(fs, "empty.toml")
+	require.EqualError(t, err, "No rules are defined in empty.toml")
+
+	afero.WriteFile(fs, "invalid.toml", []byte("[[rules]]\nvalue = \"foo\"\n"), 0644)
+	_, err = loadTransformsFile(fs, "invalid.toml")
+	require.EqualError(t, err, "Invalid transforms file invalid.toml: rule #1 must specify exactly one of set, delete, header or replace")
+}
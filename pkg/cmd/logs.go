@@ -23,10 +23,12 @@ func newLogsCmd(config *config.Config) *LogsCmd {
 		Use:   "logs",
 		Args:  validators.NoArgs,
 		Short: "Interact with Stripe API request logs",
-		Long:  `Tail Stripe API request logs in real-time and see debug information.`,
+		Long:  `Tail Stripe API request logs in real-time and see debug information, or view saved request logs.`,
 	}
 
 	logsCmd.Cmd.AddCommand(logs.NewTailCmd(logsCmd.cfg).Cmd)
+	logsCmd.Cmd.AddCommand(logs.NewTraceCmd(logsCmd.cfg).Cmd)
+	logsCmd.Cmd.AddCommand(logs.NewViewCmd().Cmd)
 
 	return logsCmd
 }
@@ -1,10 +1,13 @@
 package logs
 
 import (
+	"io"
+	"os"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"context"
 
@@ -21,10 +24,13 @@ type TailCmd struct {
 	apiBaseURL string
 	cfg        *config.Config
 	Cmd        *cobra.Command
+	filters    []string
 	format     string
 	livemode   bool
 	LogFilters *logTailing.LogFilters
+	stats      bool
 	noWSS      bool
+	outputFile string
 }
 
 // NewTailCmd creates and initializes the tail command for the logs package
@@ -43,7 +49,12 @@ Log tailing allows you to filter data similarly to the Stripe Dashboard; filter
 HTTP methods, IP addresses, paths, response status, and more.`,
 		Example: `stripe logs tail
   stripe logs tail --filter-http-methods GET
-  stripe logs tail --filter-status-code-type 4XX`,
+  stripe logs tail --filter-status-code-type 4XX
+  stripe logs tail --filter-request-path '/v1/payment_intents/*/confirm'
+  stripe logs tail --filter 'status == 4XX and status != 404 and url ~ ^/v1/customers'
+  stripe logs tail --filter 'error.code == card_declined'
+  stripe logs tail --output-file requests.jsonl
+  stripe logs tail --stats`,
 		RunE: tailCmd.runTailCmd,
 	}
 
@@ -56,6 +67,20 @@ Acceptable values:
 	'JSON' - Output logs in JSON format`,
 	)
 
+	tailCmd.Cmd.Flags().StringVar(
+		&tailCmd.outputFile,
+		"output-file",
+		"",
+		"Save the request logs to a file, one JSON object per line, which can be viewed with 'stripe logs view'",
+	)
+
+	tailCmd.Cmd.Flags().BoolVar(
+		&tailCmd.stats,
+		"stats",
+		false,
+		"Show live stats about the request logs, by route and status code, instead of the request logs",
+	)
+
 	tailCmd.Cmd.Flags().BoolVar(
 		&tailCmd.livemode,
 		"live",
@@ -64,56 +89,17 @@ Acceptable values:
 	)
 
 	// Log filters
-	tailCmd.Cmd.Flags().StringSliceVar(
-		&tailCmd.LogFilters.FilterAccount,
-		"filter-account",
-		[]string{},
-		`*CONNECT ONLY* Filter request logs by source and destination account
-Acceptable values:
-	'connect_in'  - Incoming connect requests
-	'connect_out' - Outgoing connect requests
-	'self'        - Non-connect requests`,
-	)
-	tailCmd.Cmd.Flags().StringSliceVar(&tailCmd.LogFilters.FilterIPAddress, "filter-ip-address", []string{}, "Filter request logs by ip address")
-	tailCmd.Cmd.Flags().StringSliceVar(
-		&tailCmd.LogFilters.FilterHTTPMethod,
-		"filter-http-method",
-		[]string{},
-		`Filter request logs by http method
-Acceptable values:
-	'GET'    - HTTP get requests
-	'POST'   - HTTP post requests
-	'DELETE' - HTTP delete requests`,
-	)
-	tailCmd.Cmd.Flags().StringSliceVar(&tailCmd.LogFilters.FilterRequestPath, "filter-request-path", []string{}, "Filter request logs by request path")
-	tailCmd.Cmd.Flags().StringSliceVar(
-		&tailCmd.LogFilters.FilterRequestStatus,
-		"filter-request-status",
-		[]string{},
-		`Filter request logs by request status
-Acceptable values:
-	'SUCCEEDED' - Requests that succeeded (status codes 200, 201, 202)
-	'FAILED'    - Requests that failed`,
-	)
-	tailCmd.Cmd.Flags().StringSliceVar(
-		&tailCmd.LogFilters.FilterSource,
-		"filter-source",
-		[]string{},
-		`Filter request logs by source
-Acceptable values:
-	'API'       - Requests that came through the Stripe API
-	'DASHBOARD' - Requests that came through the Stripe Dashboard`,
-	)
-	tailCmd.Cmd.Flags().StringSliceVar(&tailCmd.LogFilters.FilterStatusCode, "filter-status-code", []string{}, "Filter request logs by status code")
-	tailCmd.Cmd.Flags().StringSliceVar(
-		&tailCmd.LogFilters.FilterStatusCodeType,
-		"filter-status-code-type",
+	addLogFilterFlags(tailCmd.Cmd.Flags(), tailCmd.LogFilters, false)
+
+	tailCmd.Cmd.Flags().StringArrayVar(
+		&tailCmd.filters,
+		"filter",
 		[]string{},
-		`Filter request logs by status code type
-Acceptable values:
-	'2XX' - All 2XX status codes
-	'4XX' - All 4XX status codes
-	'5XX' - All 5XX status codes`,
+		`Only show request logs matching an expression, evaluated locally (can be repeated)
+Expressions compare fields such as status, method, url, request_id or
+error.code with ==, !=, >, >=, <, <=, ~ (regular expression) or in (a list
+such as [GET, DELETE] or a file with one value per line), and can be
+combined with and, or, not and parentheses`,
 	)
 
 	// Hidden configuration flags, useful for dev/debugging
@@ -127,12 +113,14 @@ Acceptable values:
 }
 
 func (tailCmd *TailCmd) runTailCmd(cmd *cobra.Command, args []string) error {
-	err := tailCmd.validateArgs()
+	err := validateLogFilters(tailCmd.LogFilters)
 	if err != nil {
 		return err
 	}
 
-	err = tailCmd.convertArgs()
+	convertLogFilters(tailCmd.LogFilters)
+
+	localFilters, err := parseFilters(tailCmd.filters)
 	if err != nil {
 		return err
 	}
@@ -149,14 +137,34 @@ func (tailCmd *TailCmd) runTailCmd(cmd *cobra.Command, args []string) error {
 
 	version.CheckLatestVersion()
 
+	var outputFile io.Writer
+
+	if tailCmd.outputFile != "" {
+		f, err := os.OpenFile(tailCmd.outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		outputFile = f
+	}
+
+	var stats *logTailing.Stats
+	if tailCmd.stats {
+		stats = logTailing.NewStats()
+	}
+
 	tailer := logTailing.New(&logTailing.Config{
 		APIBaseURL:       tailCmd.apiBaseURL,
 		DeviceName:       deviceName,
 		Filters:          tailCmd.LogFilters,
+		LocalFilters:     localFilters,
 		Key:              key,
 		Log:              log.StandardLogger(),
 		NoWSS:            tailCmd.noWSS,
+		OutputFile:       outputFile,
 		OutputFormat:     strings.ToUpper(tailCmd.format),
+		Stats:            stats,
 		WebSocketFeature: requestLogsWebSocketFeature,
 	})
 
@@ -168,33 +176,104 @@ func (tailCmd *TailCmd) runTailCmd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func (tailCmd *TailCmd) validateArgs() error {
-	err := validators.CallNonEmptyArray(validators.Account, tailCmd.LogFilters.FilterAccount)
+// addLogFilterFlags adds the flags of the filters applied by Stripe. If
+// localOnly is set, only the filters that can also be applied to saved request
+// logs are added.
+func addLogFilterFlags(flags *pflag.FlagSet, logFilters *logTailing.LogFilters, localOnly bool) {
+	if !localOnly {
+		flags.StringSliceVar(
+			&logFilters.FilterAccount,
+			"filter-account",
+			[]string{},
+			`*CONNECT ONLY* Filter request logs by source and destination account
+Acceptable values:
+	'connect_in'  - Incoming connect requests
+	'connect_out' - Outgoing connect requests
+	'self'        - Non-connect requests`,
+		)
+		flags.StringSliceVar(&logFilters.FilterIPAddress, "filter-ip-address", []string{}, "Filter request logs by ip address")
+	}
+
+	flags.StringSliceVar(
+		&logFilters.FilterHTTPMethod,
+		"filter-http-method",
+		[]string{},
+		`Filter request logs by http method
+Acceptable values:
+	'GET'    - HTTP get requests
+	'POST'   - HTTP post requests
+	'DELETE' - HTTP delete requests`,
+	)
+	flags.StringSliceVar(
+		&logFilters.FilterRequestPath,
+		"filter-request-path",
+		[]string{},
+		`Filter request logs by request path
+Paths may contain wildcards matching a single segment, such as
+/v1/customers/* or /v1/payment_intents/*/confirm`,
+	)
+	flags.StringSliceVar(
+		&logFilters.FilterRequestStatus,
+		"filter-request-status",
+		[]string{},
+		`Filter request logs by request status
+Acceptable values:
+	'SUCCEEDED' - Requests that succeeded (status codes 200, 201, 202)
+	'FAILED'    - Requests that failed`,
+	)
+
+	if !localOnly {
+		flags.StringSliceVar(
+			&logFilters.FilterSource,
+			"filter-source",
+			[]string{},
+			`Filter request logs by source
+Acceptable values:
+	'API'       - Requests that came through the Stripe API
+	'DASHBOARD' - Requests that came through the Stripe Dashboard`,
+		)
+	}
+
+	flags.StringSliceVar(&logFilters.FilterStatusCode, "filter-status-code", []string{}, "Filter request logs by status code")
+	flags.StringSliceVar(
+		&logFilters.FilterStatusCodeType,
+		"filter-status-code-type",
+		[]string{},
+		`Filter request logs by status code type
+Acceptable values:
+	'2XX' - All 2XX status codes
+	'4XX' - All 4XX status codes
+	'5XX' - All 5XX status codes`,
+	)
+}
+
+func validateLogFilters(logFilters *logTailing.LogFilters) error {
+	err := validators.CallNonEmptyArray(validators.Account, logFilters.FilterAccount)
 	if err != nil {
 		return err
 	}
 
-	err = validators.CallNonEmptyArray(validators.HTTPMethod, tailCmd.LogFilters.FilterHTTPMethod)
+	err = validators.CallNonEmptyArray(validators.HTTPMethod, logFilters.FilterHTTPMethod)
 	if err != nil {
 		return err
 	}
 
-	err = validators.CallNonEmptyArray(validators.StatusCode, tailCmd.LogFilters.FilterStatusCode)
+	err = validators.CallNonEmptyArray(validators.StatusCode, logFilters.FilterStatusCode)
 	if err != nil {
 		return err
 	}
 
-	err = validators.CallNonEmptyArray(validators.StatusCodeType, tailCmd.LogFilters.FilterStatusCodeType)
+	err = validators.CallNonEmptyArray(validators.StatusCodeType, logFilters.FilterStatusCodeType)
 	if err != nil {
 		return err
 	}
 
-	err = validators.CallNonEmptyArray(validators.RequestSource, tailCmd.LogFilters.FilterSource)
+	err = validators.CallNonEmptyArray(validators.RequestSource, logFilters.FilterSource)
 	if err != nil {
 		return err
 	}
 
-	err = validators.CallNonEmptyArray(validators.RequestStatus, tailCmd.LogFilters.FilterRequestStatus)
+	err = validators.CallNonEmptyArray(validators.RequestStatus, logFilters.FilterRequestStatus)
 	if err != nil {
 		return err
 	}
@@ -202,13 +281,27 @@ func (tailCmd *TailCmd) validateArgs() error {
 	return nil
 }
 
-func (tailCmd *TailCmd) convertArgs() error {
+func convertLogFilters(logFilters *logTailing.LogFilters) {
 	// The backend expects to receive the status code type as a string representing the start of the range (e.g., '200')
-	if len(tailCmd.LogFilters.FilterStatusCodeType) > 0 {
-		for i, code := range tailCmd.LogFilters.FilterStatusCodeType {
-			tailCmd.LogFilters.FilterStatusCodeType[i] = strings.ReplaceAll(strings.ToUpper(code), "X", "0")
+	if len(logFilters.FilterStatusCodeType) > 0 {
+		for i, code := range logFilters.FilterStatusCodeType {
+			logFilters.FilterStatusCodeType[i] = strings.ReplaceAll(strings.ToUpper(code), "X", "0")
 		}
 	}
+}
 
-	return nil
+// parseFilters parses the expressions of the --filter flag.
+func parseFilters(exprs []string) ([]*logTailing.Filter, error) {
+	filters := make([]*logTailing.Filter, 0, len(exprs))
+
+	for _, expr := range exprs {
+		filter, err := logTailing.ParseFilter(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
 }
@@ -0,0 +1,121 @@
+package logs
+
+import (
+	"context"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/stripe/stripe-cli/pkg/config"
+	logTailing "github.com/stripe/stripe-cli/pkg/logtailing"
+	"github.com/stripe/stripe-cli/pkg/validators"
+	"github.com/stripe/stripe-cli/pkg/version"
+)
+
+// TraceCmd wraps the configuration for the trace command
+type TraceCmd struct {
+	apiBaseURL string
+	cfg        *config.Config
+	Cmd        *cobra.Command
+	filters    []string
+	format     string
+	livemode   bool
+	LogFilters *logTailing.LogFilters
+	noWSS      bool
+}
+
+// NewTraceCmd creates and initializes the trace command for the logs package
+func NewTraceCmd(config *config.Config) *TraceCmd {
+	traceCmd := &TraceCmd{
+		cfg:        config,
+		LogFilters: &logTailing.LogFilters{},
+	}
+
+	traceCmd.Cmd = &cobra.Command{
+		Use:   "trace",
+		Args:  validators.NoArgs,
+		Short: "Tail API request logs along with the webhook events they trigger.",
+		Long: `View API request logs and webhook events in real-time, in chronological order.
+Events are linked to the request that caused them, when it was logged. Filters
+only apply to request logs.`,
+		Example: `stripe logs trace
+  stripe logs trace --filter-request-path '/v1/payment_intents/*/confirm'`,
+		RunE: traceCmd.runTraceCmd,
+	}
+
+	traceCmd.Cmd.Flags().StringVar(
+		&traceCmd.format,
+		"format",
+		"",
+		`Specifies the output format of request logs and events
+Acceptable values:
+	'JSON' - Output logs and events in JSON format`,
+	)
+
+	traceCmd.Cmd.Flags().BoolVar(
+		&traceCmd.livemode,
+		"live",
+		false,
+		"[WARNING: experimental] Tail live logs (default: test)",
+	)
+
+	// Log filters
+	addLogFilterFlags(traceCmd.Cmd.Flags(), traceCmd.LogFilters, false)
+
+	traceCmd.Cmd.Flags().StringArrayVar(
+		&traceCmd.filters,
+		"filter",
+		[]string{},
+		"Only show request logs matching an expression (can be repeated, see 'stripe logs tail --help')",
+	)
+
+	// Hidden configuration flags, useful for dev/debugging
+	traceCmd.Cmd.Flags().StringVar(&traceCmd.apiBaseURL, "api-base", "", "Sets the API base URL")
+	traceCmd.Cmd.Flags().MarkHidden("api-base") // #nosec G104
+
+	traceCmd.Cmd.Flags().BoolVar(&traceCmd.noWSS, "no-wss", false, "Force unencrypted ws:// protocol instead of wss://")
+	traceCmd.Cmd.Flags().MarkHidden("no-wss") // #nosec G104
+
+	return traceCmd
+}
+
+func (traceCmd *TraceCmd) runTraceCmd(cmd *cobra.Command, args []string) error {
+	err := validateLogFilters(traceCmd.LogFilters)
+	if err != nil {
+		return err
+	}
+
+	convertLogFilters(traceCmd.LogFilters)
+
+	localFilters, err := parseFilters(traceCmd.filters)
+	if err != nil {
+		return err
+	}
+
+	deviceName, err := traceCmd.cfg.Profile.GetDeviceName()
+	if err != nil {
+		return err
+	}
+
+	key, err := traceCmd.cfg.Profile.GetAPIKey(traceCmd.livemode)
+	if err != nil {
+		return err
+	}
+
+	version.CheckLatestVersion()
+
+	tracer := logTailing.NewTracer(&logTailing.Config{
+		APIBaseURL:       traceCmd.apiBaseURL,
+		DeviceName:       deviceName,
+		Filters:          traceCmd.LogFilters,
+		Key:              key,
+		LocalFilters:     localFilters,
+		Log:              log.StandardLogger(),
+		NoWSS:            traceCmd.noWSS,
+		OutputFormat:     strings.ToUpper(traceCmd.format),
+		WebSocketFeature: requestLogsWebSocketFeature,
+	})
+
+	return tracer.Run(context.Background())
+}
@@ -0,0 +1,102 @@
+package logs
+
+import (
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	logTailing "github.com/stripe/stripe-cli/pkg/logtailing"
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+// ViewCmd wraps the configuration for the view command
+type ViewCmd struct {
+	Cmd        *cobra.Command
+	filters    []string
+	format     string
+	LogFilters *logTailing.LogFilters
+	stats      bool
+}
+
+// NewViewCmd creates and initializes the view command for the logs package
+func NewViewCmd() *ViewCmd {
+	viewCmd := &ViewCmd{
+		LogFilters: &logTailing.LogFilters{},
+	}
+
+	viewCmd.Cmd = &cobra.Command{
+		Use:   "view <file>",
+		Args:  validators.ExactArgs(1),
+		Short: "View API request logs saved by logs tail.",
+		Long: `View the API request logs saved to a file with 'stripe logs tail --output-file',
+with the same format and filters as when tailing them.`,
+		Example: `stripe logs view requests.jsonl
+  stripe logs view requests.jsonl --filter-status-code-type 4XX
+  stripe logs view requests.jsonl --filter 'error.code == card_declined'
+  stripe logs view requests.jsonl --stats`,
+		RunE: viewCmd.runViewCmd,
+	}
+
+	viewCmd.Cmd.Flags().StringVar(
+		&viewCmd.format,
+		"format",
+		"",
+		`Specifies the output format of request logs
+Acceptable values:
+	'JSON' - Output logs in JSON format`,
+	)
+
+	viewCmd.Cmd.Flags().BoolVar(
+		&viewCmd.stats,
+		"stats",
+		false,
+		"Show stats about the request logs, by route and status code, instead of the request logs",
+	)
+
+	// Log filters
+	addLogFilterFlags(viewCmd.Cmd.Flags(), viewCmd.LogFilters, true)
+
+	viewCmd.Cmd.Flags().StringArrayVar(
+		&viewCmd.filters,
+		"filter",
+		[]string{},
+		"Only show request logs matching an expression (can be repeated, see 'stripe logs tail --help')",
+	)
+
+	return viewCmd
+}
+
+func (viewCmd *ViewCmd) runViewCmd(cmd *cobra.Command, args []string) error {
+	err := validateLogFilters(viewCmd.LogFilters)
+	if err != nil {
+		return err
+	}
+
+	localFilters, err := parseFilters(viewCmd.filters)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var stats *logTailing.Stats
+	if viewCmd.stats {
+		stats = logTailing.NewStats()
+	}
+
+	tailer := logTailing.New(&logTailing.Config{
+		Filters:      viewCmd.LogFilters,
+		LocalFilters: localFilters,
+		Log:          log.StandardLogger(),
+		OutputFormat: strings.ToUpper(viewCmd.format),
+		Stats:        stats,
+	})
+
+	return tailer.View(f)
+}
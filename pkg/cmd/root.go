@@ -35,6 +35,7 @@ var rootCmd = &cobra.Command{
 		"delete":    "http",
 		"trigger":   "webhooks",
 		"listen":    "webhooks",
+		"webhooks":  "webhooks",
 		"logs":      "stripe",
 		"status":    "stripe",
 		"resources": "resources",
@@ -110,6 +111,7 @@ func init() {
 	rootCmd.AddCommand(newStatusCmd().cmd)
 	rootCmd.AddCommand(newTriggerCmd().cmd)
 	rootCmd.AddCommand(newVersionCmd().cmd)
+	rootCmd.AddCommand(newWebhooksCmd().cmd)
 
 	addAllResourcesCmds(rootCmd)
 
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/stripe/stripe-cli/pkg/cmd/webhooks"
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+type webhooksCmd struct {
+	cmd *cobra.Command
+}
+
+func newWebhooksCmd() *webhooksCmd {
+	wc := &webhooksCmd{}
+
+	wc.cmd = &cobra.Command{
+		Use:   "webhooks",
+		Args:  validators.NoArgs,
+		Short: "Tools to debug webhook deliveries",
+		Long:  `Tools to help debug webhook deliveries, such as verifying signatures.`,
+	}
+
+	wc.cmd.AddCommand(webhooks.NewVerifyCmd().Cmd)
+
+	return wc
+}
@@ -0,0 +1,114 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/stripe/stripe-cli/pkg/ansi"
+	"github.com/stripe/stripe-cli/pkg/validators"
+	"github.com/stripe/stripe-cli/pkg/webhooks"
+)
+
+// VerifyCmd checks a webhook payload against its `Stripe-Signature` header
+type VerifyCmd struct {
+	Cmd *cobra.Command
+
+	fs        afero.Fs
+	stdin     io.Reader
+	out       io.Writer
+	signature string
+	secret    string
+	tolerance time.Duration
+}
+
+// NewVerifyCmd creates and returns a verify command for webhooks
+func NewVerifyCmd() *VerifyCmd {
+	verifyCmd := &VerifyCmd{
+		fs:    afero.NewOsFs(),
+		stdin: os.Stdin,
+		out:   os.Stdout,
+	}
+
+	verifyCmd.Cmd = &cobra.Command{
+		Use:   "verify [payload file]",
+		Args:  validators.MaximumNArgs(1),
+		Short: "Verify the signature of a webhook payload",
+		Long: `Verify that a webhook payload was signed with your endpoint's signing secret.
+The payload is read from the given file, or from stdin if no file is given. It
+must be the exact raw body that was delivered to your endpoint.
+
+The command exits with a non-zero status if the signature is invalid.`,
+		Example: `stripe webhooks verify payload.json --signature "t=1583423541,v1=..." --secret whsec_...
+  cat payload.json | stripe webhooks verify --signature "$SIG" --secret whsec_...`,
+		RunE: verifyCmd.runVerifyCmd,
+	}
+
+	verifyCmd.Cmd.Flags().StringVar(&verifyCmd.signature, "signature", "", "The value of the Stripe-Signature header")
+	verifyCmd.Cmd.Flags().StringVar(&verifyCmd.secret, "secret", "", "The webhook endpoint's signing secret (whsec_...)")
+	verifyCmd.Cmd.Flags().DurationVar(&verifyCmd.tolerance, "tolerance", webhooks.DefaultTolerance, "Maximum allowed age of the signature timestamp (0 to disable the check)")
+
+	return verifyCmd
+}
+
+func (vc *VerifyCmd) runVerifyCmd(cmd *cobra.Command, args []string) error {
+	if vc.signature == "" {
+		return errors.New("--signature is required")
+	}
+
+	if vc.secret == "" {
+		return errors.New("--secret is required")
+	}
+
+	err := validators.WebhookSigningSecret(vc.secret)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	if len(args) == 0 || args[0] == "-" {
+		payload, err = ioutil.ReadAll(vc.stdin)
+	} else {
+		payload, err = afero.ReadFile(vc.fs, args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	result, err := webhooks.VerifySignatureHeader(payload, vc.signature, vc.secret, vc.tolerance, time.Now())
+	if err != nil {
+		return err
+	}
+
+	color := ansi.Color(vc.out)
+
+	fmt.Fprintf(vc.out, "Timestamp: %s (%s ago)\n", result.Timestamp.Format(time.RFC3339), result.Age.Round(time.Second))
+	fmt.Fprintf(vc.out, "Schemes:   %v\n", result.Schemes)
+
+	if result.MatchedScheme != "" {
+		fmt.Fprintf(vc.out, "Signature: %s (scheme %s)\n", color.Green("matched"), result.MatchedScheme)
+	} else {
+		fmt.Fprintf(vc.out, "Signature: %s\n", color.Red("no signatures found matching the expected signature for payload"))
+	}
+
+	if result.OutsideTolerance {
+		fmt.Fprintf(vc.out, "Tolerance: %s, timestamp is outside the tolerance of %s\n", color.Red("failed"), vc.tolerance)
+	} else if vc.tolerance > 0 {
+		fmt.Fprintf(vc.out, "Tolerance: %s (%s)\n", color.Green("ok"), vc.tolerance)
+	}
+
+	if !result.Valid {
+		return errors.New("Signature verification failed")
+	}
+
+	fmt.Fprintln(vc.out, "Signature is valid")
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/webhooks"
+)
+
+const (
+	testPayload = `{"id":"evt_123","type":"customer.created"}`
+	testSecret  = "whsec_test_secret"
+)
+
+func newTestVerifyCmd(stdin string) (*VerifyCmd, *bytes.Buffer) {
+	var out bytes.Buffer
+
+	vc := NewVerifyCmd()
+	vc.fs = afero.NewMemMapFs()
+	vc.stdin = strings.NewReader(stdin)
+	vc.out = &out
+
+	return vc, &out
+}
+
+func TestVerifyPayloadFromStdin(t *testing.T) {
+	vc, out := newTestVerifyCmd(testPayload)
+
+	vc.signature = webhooks.GenerateSignatureHeader(time.Now(), []byte(testPayload), testSecret)
+	vc.secret = testSecret
+	vc.tolerance = webhooks.DefaultTolerance
+
+	require.NoError(t, vc.runVerifyCmd(vc.Cmd, []string{}))
+	require.Contains(t, out.String(), "Schemes:   [v1]")
+	require.Contains(t, out.String(), "(scheme v1)")
+	require.Contains(t, out.String(), "Signature is valid")
+}
+
+func TestVerifyPayloadFromFile(t *testing.T) {
+	vc, out := newTestVerifyCmd("")
+	afero.WriteFile(vc.fs, "payload.json", []byte(testPayload), 0644)
+
+	vc.signature = webhooks.GenerateSignatureHeader(time.Now(), []byte(testPayload), testSecret)
+	vc.secret = testSecret
+	vc.tolerance = webhooks.DefaultTolerance
+
+	require.NoError(t, vc.runVerifyCmd(vc.Cmd, []string{"payload.json"}))
+	require.Contains(t, out.String(), "Signature is valid")
+
+	// The file must hold the exact payload that was signed
+	afero.WriteFile(vc.fs, "payload.json", []byte(testPayload+"\n"), 0644)
+	require.EqualError(t, vc.runVerifyCmd(vc.Cmd, []string{"payload.json"}), "Signature verification failed")
+}
+
+func TestVerifyOutsideTolerance(t *testing.T) {
+	vc, out := newTestVerifyCmd(testPayload)
+
+	vc.signature = webhooks.GenerateSignatureHeader(time.Now().Add(-10*time.Minute), []byte(testPayload), testSecret)
+	vc.secret = testSecret
+	vc.tolerance = 5 * time.Minute
+
+	require.EqualError(t, vc.runVerifyCmd(vc.Cmd, []string{}), "Signature verification failed")
+	require.Contains(t, out.String(), "(scheme v1)")
+	require.Contains(t, out.String(), "timestamp is outside the tolerance of 5m0s")
+
+	// The age of the signature isn't checked with a tolerance of 0
+	vc, _ = newTestVerifyCmd(testPayload)
+
+	vc.signature = webhooks.GenerateSignatureHeader(time.Now().Add(-10*time.Minute), []byte(testPayload), testSecret)
+	vc.secret = testSecret
+	vc.tolerance = 0
+
+	require.NoError(t, vc.runVerifyCmd(vc.Cmd, []string{"-"}))
+}
@@ -0,0 +1,680 @@
+// Package listenui implements the interactive, full-screen interface of
+// `stripe listen --tui`.
+package listenui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/stripe/stripe-cli/pkg/ansi"
+	"github.com/stripe/stripe-cli/pkg/proxy"
+)
+
+//
+// Public types
+//
+
+// Config contains the configuration of the UI.
+type Config struct {
+	// Endpoints are the URLs events are forwarded to, one column is displayed
+	// per endpoint
+	Endpoints []string
+
+	// Resend is called when the user asks for an event to be resent to the
+	// local endpoints
+	Resend func(evt *proxy.ReceivedEvent)
+}
+
+// UI is an interactive view of the events received by the proxy and of the
+// responses of the local endpoints. It implements proxy.Observer, and
+// io.Writer so that it can be used as the output of the logger: the last
+// line written is displayed in the status bar.
+type UI struct {
+	cfg *Config
+
+	in  *os.File
+	out *os.File
+
+	mu        sync.Mutex
+	rows      []*row
+	byWebhook map[string]*row
+	ready     bool
+	secret    string
+	status    string
+	selected  int
+	offset    int
+	detail    bool
+	scroll    int
+
+	redraw chan struct{}
+
+	closeOnce sync.Once
+	closed    bool
+	oldState  *terminal.State
+}
+
+// New returns a new UI.
+func New(cfg *Config) *UI {
+	if cfg.Resend == nil {
+		cfg.Resend = func(*proxy.ReceivedEvent) {}
+	}
+
+	return &UI{
+		cfg:       cfg,
+		in:        os.Stdin,
+		out:       os.Stdout,
+		byWebhook: make(map[string]*row),
+		status:    "Getting ready...",
+		redraw:    make(chan struct{}, 1),
+	}
+}
+
+// Open switches the terminal to raw mode and to the alternate screen.
+func (ui *UI) Open() error {
+	if !terminal.IsTerminal(int(ui.in.Fd())) || !terminal.IsTerminal(int(ui.out.Fd())) {
+		return fmt.Errorf("--tui requires an interactive terminal")
+	}
+
+	oldState, err := terminal.MakeRaw(int(ui.in.Fd()))
+	if err != nil {
+		return err
+	}
+
+	ui.oldState = oldState
+
+	// Switch to the alternate screen, hide the cursor and disable line
+	// wrapping
+	fmt.Fprint(ui.out, "\x1b[?1049h\x1b[?25l\x1b[?7l")
+
+	return nil
+}
+
+// Run reads and handles key presses until the user quits, then restores the
+// terminal.
+func (ui *UI) Run() {
+	defer ui.Close()
+
+	keys := make(chan key)
+
+	go readKeys(ui.in, keys)
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		ui.draw()
+
+		select {
+		case k, ok := <-keys:
+			if !ok || ui.handleKey(k) {
+				return
+			}
+		case <-ui.redraw:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close restores the terminal to its original state. It is safe to call
+// Close several times.
+func (ui *UI) Close() {
+	ui.close()
+}
+
+// Abort restores the terminal and prints the last status message, which
+// usually explains why the program is exiting. It is meant to be used as a
+// logrus exit handler.
+func (ui *UI) Abort() {
+	if ui.close() {
+		ui.mu.Lock()
+		defer ui.mu.Unlock()
+
+		fmt.Fprintln(os.Stderr, ui.status)
+	}
+}
+
+// Write sets the status bar message. Once the UI is closed, messages are
+// written to stderr instead.
+func (ui *UI) Write(p []byte) (int, error) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	if ui.closed {
+		return os.Stderr.Write(p)
+	}
+
+	if msg := strings.TrimSpace(string(p)); msg != "" {
+		lines := strings.Split(msg, "\n")
+		ui.status = lines[len(lines)-1]
+	}
+
+	ui.notify()
+
+	return len(p), nil
+}
+
+// SessionReady is called by the proxy when the session is established.
+func (ui *UI) SessionReady(secret string) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	ui.ready = true
+	ui.secret = secret
+	ui.status = ""
+	ui.notify()
+}
+
+// SessionStatusChanged is called by the proxy when the connection with
+// Stripe is lost or established again.
+func (ui *UI) SessionStatusChanged(change *proxy.SessionStatusChange) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	switch change.Status {
+	case proxy.SessionReconnecting:
+		ui.status = fmt.Sprintf("Reconnecting (%s)...", change.Reason)
+	case proxy.SessionExpired:
+		ui.status = "Session expired, reconnecting..."
+	default:
+		// The proxy logs how long it was disconnected, which is already
+		// displayed
+		return
+	}
+
+	ui.notify()
+}
+
+// EventReceived is called by the proxy for every event it receives.
+func (ui *UI) EventReceived(evt *proxy.ReceivedEvent) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	r := &row{
+		event:     evt,
+		responses: make(map[string]*proxy.EndpointResponse),
+	}
+
+	// Follow new events when the last one is selected
+	follow := ui.selected == len(ui.rows)-1
+
+	ui.rows = append(ui.rows, r)
+	ui.byWebhook[evt.WebhookID] = r
+
+	if follow && !ui.detail {
+		ui.selected = len(ui.rows) - 1
+	}
+
+	ui.notify()
+}
+
+// DeliveryAttempted is called by the proxy before every delivery attempt.
+func (ui *UI) DeliveryAttempted(attempt *proxy.DeliveryAttempt) {}
+
+// EndpointResponded is called by the proxy when an endpoint responds to an
+// event, or when delivery fails.
+func (ui *UI) EndpointResponded(resp *proxy.EndpointResponse) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	r, ok := ui.byWebhook[resp.WebhookID]
+	if !ok {
+		return
+	}
+
+	r.responses[resp.URL] = resp
+	ui.notify()
+}
+
+//
+// Private types
+//
+
+type row struct {
+	event     *proxy.ReceivedEvent
+	responses map[string]*proxy.EndpointResponse
+}
+
+type key int
+
+const (
+	keyNone key = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyEnter
+	keyBack
+	keyResend
+	keyQuit
+)
+
+//
+// Private constants
+//
+
+const (
+	timeColumnWidth     = 8
+	typeColumnWidth     = 32
+	idColumnWidth       = 30
+	endpointColumnWidth = 18
+
+	// Number of lines used by the header and the footer of the list view
+	headerHeight = 3
+	footerHeight = 2
+)
+
+//
+// Private functions
+//
+
+// close restores the terminal and reports whether it was still open.
+func (ui *UI) close() bool {
+	closed := false
+
+	ui.closeOnce.Do(func() {
+		ui.mu.Lock()
+		ui.closed = true
+		ui.mu.Unlock()
+
+		fmt.Fprint(ui.out, "\x1b[?7h\x1b[?25h\x1b[?1049l")
+
+		if ui.oldState != nil {
+			terminal.Restore(int(ui.in.Fd()), ui.oldState) //nolint:errcheck
+		}
+
+		closed = true
+	})
+
+	return closed
+}
+
+// notify asks for the screen to be redrawn. ui.mu must be held.
+func (ui *UI) notify() {
+	select {
+	case ui.redraw <- struct{}{}:
+	default:
+	}
+}
+
+func (ui *UI) draw() {
+	width, height, err := terminal.GetSize(int(ui.out.Fd()))
+	if err != nil {
+		width, height = 120, 40
+	}
+
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	if ui.closed {
+		return
+	}
+
+	lines := ui.render(width, height)
+
+	var buf bytes.Buffer
+
+	buf.WriteString("\x1b[H\x1b[2J")
+	buf.WriteString(strings.Join(lines, "\r\n"))
+
+	ui.out.Write(buf.Bytes()) //nolint:errcheck
+}
+
+// handleKey updates the state of the UI according to k, and reports whether
+// the user asked to quit.
+func (ui *UI) handleKey(k key) bool {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	if ui.detail {
+		switch k {
+		case keyUp:
+			ui.scroll--
+		case keyDown:
+			ui.scroll++
+		case keyPageUp:
+			ui.scroll -= 10
+		case keyPageDown:
+			ui.scroll += 10
+		case keyBack:
+			ui.detail = false
+		case keyResend:
+			ui.resendSelected()
+		case keyQuit:
+			return true
+		}
+
+		if ui.scroll < 0 {
+			ui.scroll = 0
+		}
+
+		return false
+	}
+
+	switch k {
+	case keyUp:
+		ui.selected--
+	case keyDown:
+		ui.selected++
+	case keyPageUp:
+		ui.selected -= 10
+	case keyPageDown:
+		ui.selected += 10
+	case keyEnter:
+		if len(ui.rows) > 0 {
+			ui.detail = true
+			ui.scroll = 0
+		}
+	case keyResend:
+		ui.resendSelected()
+	case keyQuit:
+		return true
+	}
+
+	if ui.selected >= len(ui.rows) {
+		ui.selected = len(ui.rows) - 1
+	}
+
+	if ui.selected < 0 {
+		ui.selected = 0
+	}
+
+	return false
+}
+
+// resendSelected resends the selected event. ui.mu must be held.
+func (ui *UI) resendSelected() {
+	if ui.selected >= len(ui.rows) {
+		return
+	}
+
+	evt := ui.rows[ui.selected].event
+	ui.status = fmt.Sprintf("Resending %s...", evt.EventID)
+
+	go ui.cfg.Resend(evt)
+}
+
+// render returns the lines to display on a screen of the given size. ui.mu
+// must be held.
+func (ui *UI) render(width, height int) []string {
+	var lines []string
+
+	if ui.detail && ui.selected < len(ui.rows) {
+		lines = ui.renderDetail(ui.rows[ui.selected], height-footerHeight)
+		lines = append(lines, "", ansi.Faint("↑/↓ scroll  r resend  esc back  q quit"))
+	} else {
+		lines = ui.renderList(height - headerHeight - footerHeight)
+		lines = append(lines, "", ansi.Faint("↑/↓ select  enter details  r resend  q quit"))
+	}
+
+	if ui.status != "" {
+		lines[len(lines)-1] += "  " + ui.status
+	}
+
+	return lines
+}
+
+func (ui *UI) renderList(maxRows int) []string {
+	title := "Getting ready..."
+	if ui.secret != "" {
+		title = fmt.Sprintf("Ready! Your webhook signing secret is %s", ansi.Bold(ui.secret))
+	} else if ui.ready {
+		title = "Ready!"
+	}
+
+	header := pad("TIME", timeColumnWidth) + "  " + pad("TYPE", typeColumnWidth) + "  " + pad("ID", idColumnWidth)
+	for _, endpoint := range ui.cfg.Endpoints {
+		header += "  " + pad(endpoint, endpointColumnWidth)
+	}
+
+	lines := []string{title, "", ansi.Bold(header)}
+
+	if maxRows < 1 {
+		maxRows = 1
+	}
+
+	// Keep the selected row visible
+	if ui.selected < ui.offset {
+		ui.offset = ui.selected
+	}
+
+	if ui.selected >= ui.offset+maxRows {
+		ui.offset = ui.selected - maxRows + 1
+	}
+
+	for i := ui.offset; i < len(ui.rows) && i < ui.offset+maxRows; i++ {
+		r := ui.rows[i]
+
+		eventType := r.event.EventType
+		if r.event.Replayed {
+			eventType += " (resent)"
+		} else if r.event.Backfilled {
+			eventType += " (backfilled)"
+		}
+
+		line := pad(r.event.Time.Format("15:04:05"), timeColumnWidth) + "  " +
+			pad(eventType, typeColumnWidth) + "  " +
+			pad(r.event.EventID, idColumnWidth)
+
+		if i == ui.selected {
+			line = "\x1b[7m" + line + "\x1b[27m"
+		}
+
+		for _, endpoint := range ui.cfg.Endpoints {
+			line += "  " + responseCell(r.responses[endpoint])
+		}
+
+		lines = append(lines, line)
+	}
+
+	for len(lines) < maxRows+headerHeight {
+		lines = append(lines, "")
+	}
+
+	return lines
+}
+
+func (ui *UI) renderDetail(r *row, maxLines int) []string {
+	evt := r.event
+
+	lines := []string{
+		ansi.Bold(fmt.Sprintf("%s [%s]", evt.EventType, evt.EventID)),
+		ansi.Faint(fmt.Sprintf("Received at %s", evt.Time.Format("2006-01-02 15:04:05"))),
+		"",
+		ansi.Bold("Request headers"),
+	}
+
+	lines = append(lines, formatHeaders(evt.Headers)...)
+	lines = append(lines, "", ansi.Bold("Payload"))
+	lines = append(lines, formatBody(evt.Payload)...)
+
+	for _, endpoint := range ui.cfg.Endpoints {
+		resp, ok := r.responses[endpoint]
+		if !ok {
+			continue
+		}
+
+		lines = append(lines, "", ansi.Bold("Response from "+endpoint))
+
+		if resp.Err != nil {
+			lines = append(lines, fmt.Sprintf("  %s %v", ansi.Color(os.Stdout).Red("ERROR"), resp.Err))
+			continue
+		}
+
+		summary := fmt.Sprintf("  [%d] in %s", ansi.ColorizeStatus(resp.Status), formatLatency(resp.Latency))
+		if resp.Attempts > 1 {
+			summary += fmt.Sprintf(" (after %d attempts)", resp.Attempts)
+		}
+
+		lines = append(lines, summary)
+		lines = append(lines, formatHeaders(resp.Headers)...)
+		lines = append(lines, formatBody(resp.Body)...)
+	}
+
+	if maxLines < 1 {
+		maxLines = 1
+	}
+
+	if ui.scroll > len(lines)-maxLines {
+		ui.scroll = len(lines) - maxLines
+	}
+
+	if ui.scroll < 0 {
+		ui.scroll = 0
+	}
+
+	lines = lines[ui.scroll:]
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+
+	for len(lines) < maxLines {
+		lines = append(lines, "")
+	}
+
+	return lines
+}
+
+func responseCell(resp *proxy.EndpointResponse) string {
+	switch {
+	case resp == nil:
+		return ansi.Faint(pad("…", endpointColumnWidth))
+	case resp.Err != nil:
+		return ansi.Color(os.Stdout).Red(pad("[ERR]", endpointColumnWidth)).String()
+	default:
+		status := fmt.Sprintf("%d", resp.Status)
+		plain := fmt.Sprintf("[%s] %s", status, formatLatency(resp.Latency))
+
+		return strings.Replace(pad(plain, endpointColumnWidth), status, ansi.ColorizeStatus(resp.Status).String(), 1)
+	}
+}
+
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+func formatHeaders(headers map[string]string) []string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("  %s: %s", ansi.Faint(k), headers[k]))
+	}
+
+	return lines
+}
+
+// formatBody pretty-prints and colorizes body if it is JSON, and returns it
+// as indented lines.
+func formatBody(body string) []string {
+	if body == "" {
+		return []string{ansi.Faint("  (empty)")}
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(body), "", "  "); err == nil {
+		body = ansi.ColorizeJSON(indented.String(), false, os.Stdout)
+	}
+
+	var lines []string
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		lines = append(lines, "  "+scanner.Text())
+	}
+
+	return lines
+}
+
+// pad truncates or pads s with spaces so that it is exactly width runes long.
+func pad(s string, width int) string {
+	runes := []rune(s)
+
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+
+	return s + strings.Repeat(" ", width-len(runes))
+}
+
+// readKeys reads key presses from r and sends them on keys, until r is closed.
+func readKeys(r io.Reader, keys chan<- key) {
+	defer close(keys)
+
+	buf := make([]byte, 16)
+
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for _, k := range parseKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// parseKeys converts the bytes read from a terminal in raw mode to keys.
+func parseKeys(b []byte) []key {
+	var keys []key
+
+	for len(b) > 0 {
+		switch {
+		case bytes.HasPrefix(b, []byte("\x1b[A")), bytes.HasPrefix(b, []byte("\x1bOA")):
+			keys, b = append(keys, keyUp), b[3:]
+		case bytes.HasPrefix(b, []byte("\x1b[B")), bytes.HasPrefix(b, []byte("\x1bOB")):
+			keys, b = append(keys, keyDown), b[3:]
+		case bytes.HasPrefix(b, []byte("\x1b[5~")):
+			keys, b = append(keys, keyPageUp), b[4:]
+		case bytes.HasPrefix(b, []byte("\x1b[6~")):
+			keys, b = append(keys, keyPageDown), b[4:]
+		case b[0] == 0x1b && len(b) > 1 && (b[1] == '[' || b[1] == 'O'):
+			// Unsupported escape sequence, skip it
+			return keys
+		default:
+			keys, b = append(keys, parseKey(b[0])), b[1:]
+		}
+	}
+
+	return keys
+}
+
+func parseKey(c byte) key {
+	switch c {
+	case 'k':
+		return keyUp
+	case 'j':
+		return keyDown
+	case '\r', '\n':
+		return keyEnter
+	case 0x1b, 0x7f, 'h':
+		return keyBack
+	case 'r':
+		return keyResend
+	case 'q', 0x03:
+		return keyQuit
+	default:
+		return keyNone
+	}
+}
@@ -0,0 +1,92 @@
+package listenui
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/proxy"
+)
+
+func TestParseKeys(t *testing.T) {
+	require.Equal(t, []key{keyUp, keyDown}, parseKeys([]byte("\x1b[A\x1b[B")))
+	require.Equal(t, []key{keyPageUp, keyPageDown}, parseKeys([]byte("\x1b[5~\x1b[6~")))
+	require.Equal(t, []key{keyUp, keyDown, keyEnter, keyResend, keyQuit}, parseKeys([]byte("kj\rrq")))
+	require.Equal(t, []key{keyBack}, parseKeys([]byte("\x1b")))
+	require.Equal(t, []key{keyQuit}, parseKeys([]byte{0x03}))
+}
+
+func TestEventsAndResponses(t *testing.T) {
+	ui := New(&Config{Endpoints: []string{"http://localhost:4000"}})
+
+	ui.SessionReady("whsec_123")
+	ui.EventReceived(&proxy.ReceivedEvent{
+		Time:      time.Now(),
+		WebhookID: "wh_1",
+		EventID:   "evt_1",
+		EventType: "customer.created",
+		Payload:   `{"id":"evt_1"}`,
+	})
+	ui.EventReceived(&proxy.ReceivedEvent{
+		Time:      time.Now(),
+		WebhookID: "wh_2",
+		EventID:   "evt_2",
+		EventType: "customer.updated",
+		Payload:   `{"id":"evt_2"}`,
+	})
+	ui.EndpointResponded(&proxy.EndpointResponse{
+		WebhookID: "wh_1",
+		URL:       "http://localhost:4000",
+		Status:    http.StatusOK,
+		Body:      `{"received":true}`,
+		Latency:   42 * time.Millisecond,
+		Attempts:  1,
+	})
+
+	// The last event is followed
+	require.Equal(t, 1, ui.selected)
+
+	screen := strings.Join(ui.render(200, 20), "\n")
+	require.Contains(t, screen, "whsec_123")
+	require.Contains(t, screen, "customer.created")
+	require.Contains(t, screen, "evt_2")
+	require.Contains(t, screen, "42ms")
+
+	require.False(t, ui.handleKey(keyUp))
+	require.Equal(t, 0, ui.selected)
+
+	require.False(t, ui.handleKey(keyEnter))
+	require.True(t, ui.detail)
+
+	screen = strings.Join(ui.render(200, 40), "\n")
+	require.Contains(t, screen, "evt_1")
+	require.Contains(t, screen, "received")
+
+	require.False(t, ui.handleKey(keyBack))
+	require.False(t, ui.detail)
+
+	require.True(t, ui.handleKey(keyQuit))
+}
+
+func TestResend(t *testing.T) {
+	resent := make(chan *proxy.ReceivedEvent, 1)
+
+	ui := New(&Config{
+		Resend: func(evt *proxy.ReceivedEvent) {
+			resent <- evt
+		},
+	})
+
+	ui.EventReceived(&proxy.ReceivedEvent{WebhookID: "wh_1", EventID: "evt_1"})
+	ui.handleKey(keyResend)
+
+	require.Equal(t, "evt_1", (<-resent).EventID)
+}
+
+func TestPad(t *testing.T) {
+	require.Equal(t, "abc  ", pad("abc", 5))
+	require.Equal(t, "abcd…", pad("abcdefgh", 5))
+}
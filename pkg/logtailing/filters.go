@@ -0,0 +1,486 @@
+package logtailing
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//
+// Public types
+//
+
+// Filter is a condition on request logs that is evaluated locally, unlike
+// LogFilters which are applied by Stripe. Filters compare the fields of the
+// request log, designated by their JSON names (e.g. status, url or
+// error.code), and can be combined with boolean operators:
+//
+//	status >= 400 and status != 404 and url ~ ^/v1/customers
+//	error.code == card_declined or error.decline_code == insufficient_funds
+//	not (method == GET) and request_id in requests.txt
+//
+// The supported operators are == and != (which also accept status classes
+// such as 4XX), >, >=, < and <= (numeric), ~ and !~ (regular expression
+// match), and in, which takes either a list such as [GET, DELETE] or a file
+// containing one value per line. Boolean expressions use and, or, not (or
+// &&, ||, !) and parentheses. Values containing spaces or parentheses must
+// be quoted.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// Matches reports whether a request log satisfies the filter.
+func (f *Filter) Matches(payload *EventPayload) bool {
+	return f.root.matches(payload)
+}
+
+// String returns the expression of the filter.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+//
+// Public functions
+//
+
+// ParseFilter parses a filter expression. Files referenced by the in
+// operator are read immediately.
+func ParseFilter(expr string) (*Filter, error) {
+	p := &filterParser{input: expr}
+
+	root, err := p.parseOr()
+	if err == nil && !p.done() {
+		err = fmt.Errorf("unexpected %q", p.input[p.pos:])
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", expr, err)
+	}
+
+	return &Filter{expr: expr, root: root}, nil
+}
+
+//
+// Private types
+//
+
+type filterNode interface {
+	matches(payload *EventPayload) bool
+}
+
+type andNode struct {
+	left, right filterNode
+}
+
+func (n *andNode) matches(payload *EventPayload) bool {
+	return n.left.matches(payload) && n.right.matches(payload)
+}
+
+type orNode struct {
+	left, right filterNode
+}
+
+func (n *orNode) matches(payload *EventPayload) bool {
+	return n.left.matches(payload) || n.right.matches(payload)
+}
+
+type notNode struct {
+	node filterNode
+}
+
+func (n *notNode) matches(payload *EventPayload) bool {
+	return !n.node.matches(payload)
+}
+
+type comparisonNode struct {
+	field    []int
+	operator string
+	value    string
+	regexp   *regexp.Regexp
+	set      map[string]bool
+}
+
+func (n *comparisonNode) matches(payload *EventPayload) bool {
+	actual := fmt.Sprint(reflect.ValueOf(payload).Elem().FieldByIndex(n.field).Interface())
+
+	switch n.operator {
+	case "==", "!=":
+		return valuesEqual(actual, n.value) == (n.operator == "==")
+	case "~", "!~":
+		return n.regexp.MatchString(actual) == (n.operator == "~")
+	case "in":
+		return n.set[actual]
+	default:
+		a, errA := strconv.ParseFloat(actual, 64)
+		b, errB := strconv.ParseFloat(n.value, 64)
+
+		if errA != nil || errB != nil {
+			return false
+		}
+
+		switch n.operator {
+		case ">":
+			return a > b
+		case ">=":
+			return a >= b
+		case "<":
+			return a < b
+		default:
+			return a <= b
+		}
+	}
+}
+
+// filterParser is a recursive descent parser for filter expressions.
+type filterParser struct {
+	input string
+	pos   int
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.consumeKeyword("or", "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.consumeKeyword("and", "&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.consumeKeyword("not", "!") {
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &notNode{node: node}, nil
+	}
+
+	p.skipSpaces()
+
+	if p.peek() == '(' {
+		p.pos++
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpaces()
+
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+
+		p.pos++
+
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	p.skipSpaces()
+
+	start := p.pos
+	for !p.done() && (isFieldChar(rune(p.input[p.pos]))) {
+		p.pos++
+	}
+
+	name := p.input[start:p.pos]
+	if name == "" {
+		if p.done() {
+			return nil, fmt.Errorf("expected a field")
+		}
+
+		return nil, fmt.Errorf("expected a field at %q", p.input[p.pos:])
+	}
+
+	field, err := lookupField(name)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+
+	operator := ""
+
+	for _, op := range []string{"==", "!=", ">=", "<=", "!~", ">", "<", "~"} {
+		if strings.HasPrefix(p.input[p.pos:], op) {
+			operator = op
+			break
+		}
+	}
+
+	if operator == "" && p.consumeKeyword("in") {
+		return p.parseIn(field)
+	}
+
+	if operator == "" {
+		return nil, fmt.Errorf("expected an operator after %s", name)
+	}
+
+	p.pos += len(operator)
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &comparisonNode{field: field, operator: operator, value: value}
+
+	if operator == "~" || operator == "!~" {
+		node.regexp, err = regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", value, err)
+		}
+	}
+
+	return node, nil
+}
+
+// parseIn parses the operand of the in operator: a list of values in
+// brackets, or the path of a file with one value per line.
+func (p *filterParser) parseIn(field []int) (filterNode, error) {
+	node := &comparisonNode{field: field, operator: "in", set: make(map[string]bool)}
+
+	p.skipSpaces()
+
+	if p.peek() != '[' {
+		path, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := readValuesFile(path, node.set); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+	}
+
+	p.pos++
+
+	for {
+		p.skipSpaces()
+
+		if p.peek() == ']' {
+			p.pos++
+			return node, nil
+		}
+
+		value, err := p.parseListValue()
+		if err != nil {
+			return nil, err
+		}
+
+		node.set[value] = true
+
+		p.skipSpaces()
+
+		if p.peek() == ',' {
+			p.pos++
+		}
+	}
+}
+
+// parseValue parses a quoted value, or an unquoted one up to the next space
+// or closing parenthesis.
+func (p *filterParser) parseValue() (string, error) {
+	return p.parseValueUntil(" \t)")
+}
+
+func (p *filterParser) parseListValue() (string, error) {
+	return p.parseValueUntil(" \t,]")
+}
+
+func (p *filterParser) parseValueUntil(stops string) (string, error) {
+	p.skipSpaces()
+
+	if p.done() {
+		return "", fmt.Errorf("expected a value")
+	}
+
+	if quote := p.peek(); quote == '"' || quote == '\'' {
+		end := strings.IndexByte(p.input[p.pos+1:], quote)
+		if end == -1 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+
+		value := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+
+		return value, nil
+	}
+
+	start := p.pos
+	for !p.done() && !strings.ContainsRune(stops, rune(p.input[p.pos])) {
+		p.pos++
+	}
+
+	if p.pos == start {
+		return "", fmt.Errorf("expected a value at %q", p.input[p.pos:])
+	}
+
+	return p.input[start:p.pos], nil
+}
+
+// consumeKeyword consumes one of the given keywords if it comes next, as a
+// whole word for alphabetic keywords.
+func (p *filterParser) consumeKeyword(keywords ...string) bool {
+	p.skipSpaces()
+
+	for _, keyword := range keywords {
+		if !strings.HasPrefix(strings.ToLower(p.input[p.pos:]), keyword) {
+			continue
+		}
+
+		end := p.pos + len(keyword)
+
+		if unicode.IsLetter(rune(keyword[0])) && end < len(p.input) && isFieldChar(rune(p.input[end])) {
+			continue
+		}
+
+		// `!` is negation, but `!=` and `!~` are operators
+		if keyword == "!" && end < len(p.input) && (p.input[end] == '=' || p.input[end] == '~') {
+			continue
+		}
+
+		p.pos = end
+
+		return true
+	}
+
+	return false
+}
+
+func (p *filterParser) skipSpaces() {
+	for !p.done() && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *filterParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+
+	return p.input[p.pos]
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+//
+// Private variables
+//
+
+var statusClassRegexp = regexp.MustCompile(`^[1-5][xX][xX]$`)
+
+//
+// Private functions
+//
+
+// lookupField returns the index of the request log field with the given JSON
+// path, e.g. error.code.
+func lookupField(name string) ([]int, error) {
+	t := reflect.TypeOf(EventPayload{})
+	index := make([]int, 0)
+
+	for i, part := range strings.Split(name, ".") {
+		found := false
+
+		for j := 0; j < t.NumField(); j++ {
+			if strings.Split(t.Field(j).Tag.Get("json"), ",")[0] == part {
+				index = append(index, j)
+				t = t.Field(j).Type
+				found = true
+
+				break
+			}
+		}
+
+		if !found || (t.Kind() == reflect.Struct) != (i < len(strings.Split(name, "."))-1) {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+	}
+
+	return index, nil
+}
+
+func isFieldChar(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// valuesEqual compares values as numbers if they both are, as a status
+// class if expected is one (e.g. 4XX), or as strings otherwise.
+func valuesEqual(actual, expected string) bool {
+	if a, err := strconv.ParseFloat(actual, 64); err == nil {
+		if b, err := strconv.ParseFloat(expected, 64); err == nil {
+			return a == b
+		}
+
+		if statusClassRegexp.MatchString(expected) {
+			return len(actual) == 3 && actual[0] == expected[0]
+		}
+	}
+
+	return actual == expected
+}
+
+// readValuesFile adds the values found in a file, one per line, to set.
+// Blank lines and lines starting with # are ignored.
+func readValuesFile(path string, set map[string]bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		set[line] = true
+	}
+
+	return scanner.Err()
+}
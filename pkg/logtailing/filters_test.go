@@ -0,0 +1,104 @@
+package logtailing
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func filterMatches(t *testing.T, expr string, payload *EventPayload) bool {
+	filter, err := ParseFilter(expr)
+	require.NoError(t, err)
+
+	return filter.Matches(payload)
+}
+
+func TestFilterComparisons(t *testing.T) {
+	payload := &EventPayload{
+		Method:    "POST",
+		RequestID: "req_123",
+		Status:    402,
+		URL:       "/v1/payment_intents/pi_123/confirm",
+		Error: RedactedError{
+			Code:        "card_declined",
+			DeclineCode: "insufficient_funds",
+			Message:     "Your card has insufficient funds.",
+		},
+	}
+
+	require.True(t, filterMatches(t, "error.code == card_declined", payload))
+	require.False(t, filterMatches(t, "error.code != card_declined", payload))
+	require.True(t, filterMatches(t, "status == 402", payload))
+	require.True(t, filterMatches(t, "status == 4xx", payload))
+	require.False(t, filterMatches(t, "status == 5XX", payload))
+	require.True(t, filterMatches(t, "status>=400", payload))
+	require.False(t, filterMatches(t, "status < 400", payload))
+	require.False(t, filterMatches(t, "method > 400", payload))
+	require.True(t, filterMatches(t, "url ~ ^/v1/payment_intents", payload))
+	require.True(t, filterMatches(t, "url !~ ^/v1/customers", payload))
+	require.True(t, filterMatches(t, `error.message == "Your card has insufficient funds."`, payload))
+	require.True(t, filterMatches(t, "method in [GET, POST]", payload))
+	require.False(t, filterMatches(t, "method in [GET,DELETE]", payload))
+	require.True(t, filterMatches(t, "livemode == false", payload))
+}
+
+func TestFilterBooleanOperators(t *testing.T) {
+	expr := "status == 4XX and not status == 404 and url ~ ^/v1/customers"
+
+	require.True(t, filterMatches(t, expr, &EventPayload{Status: 400, URL: "/v1/customers"}))
+	require.False(t, filterMatches(t, expr, &EventPayload{Status: 404, URL: "/v1/customers"}))
+	require.False(t, filterMatches(t, expr, &EventPayload{Status: 400, URL: "/v1/charges"}))
+	require.False(t, filterMatches(t, expr, &EventPayload{Status: 200, URL: "/v1/customers"}))
+
+	// and binds tighter than or
+	expr = "method == GET || method == POST && status == 200"
+
+	require.True(t, filterMatches(t, expr, &EventPayload{Method: "GET", Status: 500}))
+	require.False(t, filterMatches(t, expr, &EventPayload{Method: "POST", Status: 500}))
+
+	expr = "(method == GET or method == POST) and !(status == 200)"
+
+	require.True(t, filterMatches(t, expr, &EventPayload{Method: "POST", Status: 500}))
+	require.False(t, filterMatches(t, expr, &EventPayload{Method: "GET", Status: 200}))
+}
+
+func TestFilterInFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filters")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "requests.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("# Failed requests\nreq_123\n\n  req_456  \n"), 0600))
+
+	expr := "request_id in " + path
+
+	require.True(t, filterMatches(t, expr, &EventPayload{RequestID: "req_123"}))
+	require.True(t, filterMatches(t, expr, &EventPayload{RequestID: "req_456"}))
+	require.False(t, filterMatches(t, expr, &EventPayload{RequestID: "req_789"}))
+
+	_, err = ParseFilter("request_id in " + filepath.Join(dir, "missing.txt"))
+	require.Error(t, err)
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"status",
+		"status ==",
+		"foo == bar",
+		"error == card_declined",
+		"error.code.foo == bar",
+		"url ~ (",
+		"(status == 200",
+		"status == 200 status == 201",
+		"method in [GET",
+		`error.message == "unterminated`,
+	} {
+		_, err := ParseFilter(expr)
+		require.Error(t, err, expr)
+	}
+}
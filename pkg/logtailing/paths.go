@@ -0,0 +1,68 @@
+package logtailing
+
+import (
+	"path"
+	"strings"
+
+	"github.com/stripe/stripe-cli/pkg/requests"
+)
+
+//
+// Private functions
+//
+
+// isPathPattern reports whether a request path filter contains wildcards,
+// which Stripe doesn't support.
+func isPathPattern(filter string) bool {
+	return strings.ContainsAny(filter, "*?[")
+}
+
+// splitPathFilters returns the filters to send to Stripe and, if some of the
+// request path filters are patterns, the filters to apply locally instead.
+// Since the request logs matching any of the path filters are shown, they
+// are all applied locally in that case.
+func splitPathFilters(filters *LogFilters) (*LogFilters, *LogFilters) {
+	if filters == nil {
+		return nil, nil
+	}
+
+	for _, filter := range filters.FilterRequestPath {
+		if isPathPattern(filter) {
+			serverFilters := *filters
+			serverFilters.FilterRequestPath = nil
+
+			return &serverFilters, &LogFilters{FilterRequestPath: filters.FilterRequestPath}
+		}
+	}
+
+	return filters, nil
+}
+
+// matchesRequestPath reports whether the path of a request matches one of the
+// given filters, where * matches a single path segment. The path is also
+// matched with the IDs of known objects collapsed, so that
+// /v1/payment_intents/*/confirm matches
+// /v1/payment_intents/pi_123/confirm.
+func matchesRequestPath(filters []string, url string) bool {
+	collapsed := requests.CollapseObjectIDs(url)
+
+	if i := strings.IndexByte(url, '?'); i != -1 {
+		url = url[:i]
+	}
+
+	for _, filter := range filters {
+		if filter == url || filter == collapsed {
+			return true
+		}
+
+		if matched, _ := path.Match(filter, url); matched {
+			return true
+		}
+
+		if matched, _ := path.Match(filter, collapsed); matched {
+			return true
+		}
+	}
+
+	return false
+}
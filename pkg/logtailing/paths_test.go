@@ -0,0 +1,43 @@
+package logtailing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitPathFilters(t *testing.T) {
+	filters := &LogFilters{
+		FilterHTTPMethod:  []string{"POST"},
+		FilterRequestPath: []string{"/v1/charges"},
+	}
+
+	serverFilters, localFilters := splitPathFilters(filters)
+	require.Equal(t, filters, serverFilters)
+	require.Nil(t, localFilters)
+
+	filters.FilterRequestPath = []string{"/v1/charges", "/v1/customers/*"}
+
+	serverFilters, localFilters = splitPathFilters(filters)
+	require.Equal(t, &LogFilters{FilterHTTPMethod: []string{"POST"}}, serverFilters)
+	require.Equal(t, &LogFilters{FilterRequestPath: []string{"/v1/charges", "/v1/customers/*"}}, localFilters)
+	require.Equal(t, []string{"/v1/charges", "/v1/customers/*"}, filters.FilterRequestPath)
+
+	serverFilters, localFilters = splitPathFilters(nil)
+	require.Nil(t, serverFilters)
+	require.Nil(t, localFilters)
+}
+
+func TestMatchesRequestPath(t *testing.T) {
+	filters := []string{"/v1/charges", "/v1/customers/*", "/v1/payment_intents/*/confirm"}
+
+	require.True(t, matchesRequestPath(filters, "/v1/charges"))
+	require.True(t, matchesRequestPath(filters, "/v1/customers/cus_123"))
+	require.True(t, matchesRequestPath(filters, "/v1/customers/cus_123?expand[]=sources"))
+	require.True(t, matchesRequestPath(filters, "/v1/payment_intents/pi_123/confirm"))
+	require.False(t, matchesRequestPath(filters, "/v1/charges/ch_123"))
+	require.False(t, matchesRequestPath(filters, "/v1/customers/cus_123/sources"))
+	require.False(t, matchesRequestPath(filters, "/v1/payment_intents/pi_123/capture"))
+
+	require.True(t, matchesRequestPath([]string{"/v1/customers/cus_*/sources"}, "/v1/customers/cus_123/sources"))
+}
@@ -0,0 +1,270 @@
+package logtailing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stripe/stripe-cli/pkg/requests"
+)
+
+//
+// Public types
+//
+
+// Stats aggregates request logs by route (method and path, with object IDs
+// collapsed) and by status code. It is safe for concurrent use.
+type Stats struct {
+	mu sync.Mutex
+
+	routes   map[statsRoute]*routeStats
+	statuses map[int]int
+
+	total  int
+	errors int
+
+	// first and last are the creation times of the oldest and newest request
+	first int
+	last  int
+}
+
+// Add aggregates a request log.
+func (s *Stats) Add(payload *EventPayload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	route := statsRoute{method: payload.Method, path: requests.CollapseObjectIDs(payload.URL)}
+
+	rs, ok := s.routes[route]
+	if !ok {
+		rs = &routeStats{errorCodes: make(map[string]int)}
+		s.routes[route] = rs
+	}
+
+	rs.count++
+	s.total++
+	s.statuses[payload.Status]++
+
+	if payload.Status >= 400 {
+		rs.errors++
+		s.errors++
+
+		if payload.Error.Code != "" {
+			rs.errorCodes[payload.Error.Code]++
+		}
+	}
+
+	if s.total == 1 || payload.CreatedAt < s.first {
+		s.first = payload.CreatedAt
+	}
+
+	if payload.CreatedAt > s.last {
+		s.last = payload.CreatedAt
+	}
+}
+
+// Render writes a summary of the aggregated request logs.
+func (s *Stats) Render(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	minutes := s.minutes()
+
+	fmt.Fprintf(w, "Requests: %d over %s (%.1f/min)   Errors: %d (%s)\n\n",
+		s.total, time.Duration(s.last-s.first)*time.Second, float64(s.total)/minutes, s.errors, errorRate(s.errors, s.total))
+
+	routes := make([]statsRoute, 0, len(s.routes))
+	for route := range s.routes {
+		routes = append(routes, route)
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		a, b := s.routes[routes[i]], s.routes[routes[j]]
+		if a.count != b.count {
+			return a.count > b.count
+		}
+
+		return routes[i].String() < routes[j].String()
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "METHOD\tPATH\tCOUNT\tERRORS\tREQ/MIN\tTOP ERROR CODES")
+
+	for _, route := range routes {
+		rs := s.routes[route]
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%.1f\t%s\n",
+			route.method, route.path, rs.count, errorRate(rs.errors, rs.count), float64(rs.count)/minutes, rs.topErrorCodes())
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	statuses := make([]int, 0, len(s.statuses))
+	for status := range s.statuses {
+		statuses = append(statuses, status)
+	}
+
+	sort.Ints(statuses)
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "\nSTATUS\tCOUNT")
+
+	for _, status := range statuses {
+		fmt.Fprintf(tw, "%d\t%d\n", status, s.statuses[status])
+	}
+
+	return tw.Flush()
+}
+
+//
+// Public functions
+//
+
+// NewStats returns empty request log stats.
+func NewStats() *Stats {
+	return &Stats{
+		routes:   make(map[statsRoute]*routeStats),
+		statuses: make(map[int]int),
+	}
+}
+
+//
+// Private types
+//
+
+type statsRoute struct {
+	method string
+	path   string
+}
+
+func (r statsRoute) String() string {
+	return r.method + " " + r.path
+}
+
+type routeStats struct {
+	count      int
+	errors     int
+	errorCodes map[string]int
+}
+
+// topErrorCodes returns the most frequent error codes of a route.
+func (rs *routeStats) topErrorCodes() string {
+	codes := make([]string, 0, len(rs.errorCodes))
+	for code := range rs.errorCodes {
+		codes = append(codes, code)
+	}
+
+	sort.Slice(codes, func(i, j int) bool {
+		if rs.errorCodes[codes[i]] != rs.errorCodes[codes[j]] {
+			return rs.errorCodes[codes[i]] > rs.errorCodes[codes[j]]
+		}
+
+		return codes[i] < codes[j]
+	})
+
+	if len(codes) > maxTopErrorCodes {
+		codes = codes[:maxTopErrorCodes]
+	}
+
+	for i, code := range codes {
+		codes[i] = fmt.Sprintf("%s (%d)", code, rs.errorCodes[code])
+	}
+
+	return strings.Join(codes, ", ")
+}
+
+//
+// Private constants
+//
+
+const (
+	// maxTopErrorCodes is the number of error codes shown for each route
+	maxTopErrorCodes = 3
+
+	// statsRefreshInterval is how often stats are refreshed while tailing
+	statsRefreshInterval = time.Second
+)
+
+//
+// Private functions
+//
+
+// minutes returns the number of minutes between the oldest and newest
+// requests, used to compute rates. It is at least 1 so that the first
+// requests don't make for misleading rates.
+func (s *Stats) minutes() float64 {
+	minutes := float64(s.last-s.first) / 60
+	if minutes < 1 {
+		return 1
+	}
+
+	return minutes
+}
+
+func (s *Stats) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.total == 0
+}
+
+// refreshStats renders the stats until ctx is done, in place if the output is
+// a terminal. Once ctx is done, the stats are rendered one last time below
+// the previous output, so that they are left on screen.
+func (t *Tailer) refreshStats(ctx context.Context, out io.Writer, inPlace bool) {
+	ticker := time.NewTicker(statsRefreshInterval)
+	defer ticker.Stop()
+
+	previous := ""
+
+	for {
+		select {
+		case <-ctx.Done():
+			if !t.cfg.Stats.empty() {
+				fmt.Fprintln(out)
+				t.cfg.Stats.Render(out) //nolint:errcheck
+			}
+
+			return
+		case <-ticker.C:
+		}
+
+		// Keep the ready message until there is something to show
+		if t.cfg.Stats.empty() {
+			continue
+		}
+
+		var buf bytes.Buffer
+
+		t.cfg.Stats.Render(&buf) //nolint:errcheck
+
+		if buf.String() == previous {
+			continue
+		}
+
+		previous = buf.String()
+
+		if inPlace {
+			io.WriteString(out, "\x1b[H\x1b[2J"+previous) //nolint:errcheck
+		} else {
+			io.WriteString(out, previous+"\n") //nolint:errcheck
+		}
+	}
+}
+
+func errorRate(errors, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+
+	return fmt.Sprintf("%.1f%%", 100*float64(errors)/float64(total))
+}
@@ -0,0 +1,60 @@
+package logtailing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	stats := NewStats()
+
+	stats.Add(&EventPayload{CreatedAt: 1600000000, Method: "POST", Status: 200, URL: "/v1/payment_intents/pi_111/confirm"})
+	stats.Add(&EventPayload{CreatedAt: 1600000060, Method: "POST", Status: 402, URL: "/v1/payment_intents/pi_222/confirm", Error: RedactedError{Code: "card_declined"}})
+	stats.Add(&EventPayload{CreatedAt: 1600000090, Method: "POST", Status: 402, URL: "/v1/payment_intents/pi_333/confirm", Error: RedactedError{Code: "card_declined"}})
+	stats.Add(&EventPayload{CreatedAt: 1600000100, Method: "POST", Status: 400, URL: "/v1/payment_intents/pi_444/confirm", Error: RedactedError{Code: "parameter_missing"}})
+	stats.Add(&EventPayload{CreatedAt: 1600000120, Method: "GET", Status: 200, URL: "/v1/customers/cus_111?expand[]=sources"})
+
+	var buf bytes.Buffer
+	require.NoError(t, stats.Render(&buf))
+
+	expected := `Requests: 5 over 2m0s (2.5/min)   Errors: 3 (60.0%)
+
+METHOD  PATH                           COUNT  ERRORS  REQ/MIN  TOP ERROR CODES
+POST    /v1/payment_intents/*/confirm  4      75.0%   2.0      card_declined (2), parameter_missing (1)
+GET     /v1/customers/*                1      0.0%    0.5      
+
+STATUS  COUNT
+200     2
+400     1
+402     2
+`
+	require.Equal(t, expected, buf.String())
+}
+
+func TestStatsMinimumRateWindow(t *testing.T) {
+	stats := NewStats()
+
+	stats.Add(&EventPayload{CreatedAt: 1600000000, Method: "GET", Status: 200, URL: "/v1/charges"})
+	stats.Add(&EventPayload{CreatedAt: 1600000010, Method: "GET", Status: 200, URL: "/v1/charges"})
+
+	require.Equal(t, float64(1), stats.minutes())
+}
+
+func TestRefreshStatsRendersFinalStats(t *testing.T) {
+	stats := NewStats()
+	stats.Add(&EventPayload{CreatedAt: 1600000000, Method: "GET", Status: 200, URL: "/v1/charges"})
+
+	tailer := New(&Config{Stats: stats})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	tailer.refreshStats(ctx, &buf, true)
+
+	require.Contains(t, buf.String(), "Requests: 1")
+	require.NotContains(t, buf.String(), "\x1b[2J")
+}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"reflect"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/briandowns/spinner"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/stripe/stripe-cli/pkg/ansi"
 	"github.com/stripe/stripe-cli/pkg/stripeauth"
@@ -20,6 +24,8 @@ import (
 
 const outputFormatJSON = "JSON"
 
+const timeLayout = "2006-01-02 15:04:05"
+
 // LogFilters contains all of the potential user-provided filters for log tailing
 type LogFilters struct {
 	FilterAccount        []string `json:"filter_account,omitempty"`
@@ -45,15 +51,26 @@ type Config struct {
 	// Key is the API key used to authenticate with Stripe
 	Key string
 
+	// LocalFilters are evaluated on the received request logs, which are only
+	// shown if they match all of them
+	LocalFilters []*Filter
+
 	// Info, error, etc. logger. Unrelated to API request logs.
 	Log *log.Logger
 
 	// Force use of unencrypted ws:// protocol instead of wss://
 	NoWSS bool
 
+	// OutputFile receives each request log, as a line of JSON, if set
+	OutputFile io.Writer
+
 	// Output format for request logs
 	OutputFormat string
 
+	// Stats aggregates the request logs, which are then summarized rather
+	// than printed, if set
+	Stats *Stats
+
 	// WebSocketFeature is the feature specified for the websocket connection
 	WebSocketFeature string
 }
@@ -62,8 +79,18 @@ type Config struct {
 type Tailer struct {
 	cfg *Config
 
+	// serverFilters are the filters applied by Stripe, and localFilters the
+	// ones applied by the tailer because Stripe can't
+	serverFilters *LogFilters
+	localFilters  *LogFilters
+
+	// onRequestLog is called with the request logs that pass the filters
+	// instead of printing them, if set
+	onRequestLog func(rawPayload string, payload *EventPayload)
+
+	outputMu sync.Mutex
+
 	stripeAuthClient *stripeauth.Client
-	webSocketClient  *websocket.Client
 
 	interruptCh chan os.Signal
 }
@@ -95,8 +122,12 @@ func New(cfg *Config) *Tailer {
 		cfg.Log = &log.Logger{Out: ioutil.Discard}
 	}
 
+	serverFilters, localFilters := splitPathFilters(cfg.Filters)
+
 	return &Tailer{
-		cfg: cfg,
+		cfg:           cfg,
+		serverFilters: serverFilters,
+		localFilters:  localFilters,
 		stripeAuthClient: stripeauth.NewClient(cfg.Key, &stripeauth.Config{
 			Log:        cfg.Log,
 			APIBaseURL: cfg.APIBaseURL,
@@ -132,11 +163,49 @@ func (t *Tailer) Run(ctx context.Context) error {
 		}).Debug("Ctrl+C received, cleaning up...")
 	})
 
+	var statsDone chan struct{}
+
+	if t.cfg.Stats != nil {
+		statsDone = make(chan struct{})
+
+		go func() {
+			t.refreshStats(ctx, os.Stdout, terminal.IsTerminal(int(os.Stdout.Fd())))
+			close(statsDone)
+		}()
+	}
+
+	filters, err := jsonifyFilters(t.serverFilters)
+	if err != nil {
+		t.cfg.Log.Fatalf("Error while converting log filters to JSON encoding: %v", err)
+	}
+
+	t.runSession(ctx, s, t.cfg.WebSocketFeature, &filters, t.processRequestLogEvent, func() {
+		ansi.StopSpinner(s, "Ready! You're now waiting to receive API request logs (^C to quit)", t.cfg.Log.Out)
+	})
+
+	// Wait for the final stats to be rendered
+	if statsDone != nil {
+		<-statsDone
+	}
+
+	log.WithFields(log.Fields{
+		"prefix": "logtailing.Tailer.Run",
+	}).Debug("Bye!")
+
+	return nil
+}
+
+// runSession maintains a websocket session for a feature, creating a new
+// session when it expires, and calls ready whenever the connection is
+// established. It returns once ctx is done.
+func (t *Tailer) runSession(ctx context.Context, s *spinner.Spinner, feature string, filters *string, handler websocket.EventHandlerFunc, ready func()) {
+	var webSocketClient *websocket.Client
+
 	var warned = false
 	var nAttempts int = 0
 
 	for nAttempts < maxConnectAttempts {
-		session, err := t.createSession(ctx)
+		session, err := t.createSession(ctx, feature, filters)
 
 		if err != nil {
 			ansi.StopSpinner(s, "", t.cfg.Log.Out)
@@ -150,32 +219,36 @@ func (t *Tailer) Run(ctx context.Context) error {
 			warned = true
 		}
 
-		t.webSocketClient = websocket.NewClient(
+		webSocketClient = websocket.NewClient(
 			session.WebSocketURL,
 			session.WebSocketID,
 			session.WebSocketAuthorizedFeature,
 			&websocket.Config{
-				EventHandler:      websocket.EventHandlerFunc(t.processRequestLogEvent),
+				EventHandler:      handler,
 				Log:               t.cfg.Log,
 				NoWSS:             t.cfg.NoWSS,
 				ReconnectInterval: time.Duration(session.ReconnectDelay) * time.Second,
 			},
 		)
 
+		connected := webSocketClient.Connected()
+
 		go func() {
-			<-t.webSocketClient.Connected()
+			<-connected
 			nAttempts = 0
-			ansi.StopSpinner(s, "Ready! You're now waiting to receive API request logs (^C to quit)", t.cfg.Log.Out)
+			ready()
 		}()
 
-		go t.webSocketClient.Run(ctx)
+		go webSocketClient.Run(ctx)
 		nAttempts++
 
 		select {
 		case <-ctx.Done():
 			ansi.StopSpinner(s, "", t.cfg.Log.Out)
-			t.cfg.Log.Fatalf("Aborting")
-		case <-t.webSocketClient.NotifyExpired:
+			webSocketClient.Stop()
+
+			return
+		case <-webSocketClient.NotifyExpired:
 			if nAttempts < maxConnectAttempts {
 				ansi.StartSpinner(s, "Session expired, reconnecting...", t.cfg.Log.Out)
 			} else {
@@ -184,34 +257,23 @@ func (t *Tailer) Run(ctx context.Context) error {
 		}
 	}
 
-	if t.webSocketClient != nil {
-		t.webSocketClient.Stop()
+	if webSocketClient != nil {
+		webSocketClient.Stop()
 	}
-
-	log.WithFields(log.Fields{
-		"prefix": "logtailing.Tailer.Run",
-	}).Debug("Bye!")
-
-	return nil
 }
 
-func (t *Tailer) createSession(ctx context.Context) (*stripeauth.StripeCLISession, error) {
+func (t *Tailer) createSession(ctx context.Context, feature string, filters *string) (*stripeauth.StripeCLISession, error) {
 	var session *stripeauth.StripeCLISession
 
 	var err error
 
 	exitCh := make(chan struct{})
 
-	filters, err := jsonifyFilters(t.cfg.Filters)
-	if err != nil {
-		t.cfg.Log.Fatalf("Error while converting log filters to JSON encoding: %v", err)
-	}
-
 	go func() {
 		// Try to authorize at least 5 times before failing. Sometimes we have random
 		// transient errors that we just need to retry for.
 		for i := 0; i <= 5; i++ {
-			session, err = t.stripeAuthClient.Authorize(ctx, t.cfg.DeviceName, t.cfg.WebSocketFeature, &filters)
+			session, err = t.stripeAuthClient.Authorize(ctx, t.cfg.DeviceName, feature, filters)
 
 			if err == nil {
 				exitCh <- struct{}{}
@@ -246,8 +308,13 @@ func (t *Tailer) processRequestLogEvent(msg websocket.IncomingMessage) {
 		"webhook_id": requestLogEvent.RequestLogID,
 	}).Debugf("Processing request log event")
 
+	t.processPayload(requestLogEvent.EventPayload)
+}
+
+// processPayload records, filters and prints a request log.
+func (t *Tailer) processPayload(rawPayload string) {
 	var payload EventPayload
-	if err := json.Unmarshal([]byte(requestLogEvent.EventPayload), &payload); err != nil {
+	if err := json.Unmarshal([]byte(rawPayload), &payload); err != nil {
 		t.cfg.Log.Debug("Received malformed payload: ", err)
 	}
 
@@ -257,26 +324,59 @@ func (t *Tailer) processRequestLogEvent(msg websocket.IncomingMessage) {
 		return
 	}
 
+	if t.cfg.OutputFile != nil {
+		if err := t.record(rawPayload); err != nil {
+			t.cfg.Log.Errorf("Failed to write request log to the output file: %v", err)
+		}
+	}
+
+	if t.localFilters != nil && !matchesLogFilters(t.localFilters, &payload) {
+		t.cfg.Log.Debugf("Filtering out request log for %s %s", payload.Method, payload.URL)
+		return
+	}
+
+	for _, filter := range t.cfg.LocalFilters {
+		if !filter.Matches(&payload) {
+			t.cfg.Log.Debugf("Filtering out request log not matching %s", filter)
+			return
+		}
+	}
+
+	if t.cfg.Stats != nil {
+		t.cfg.Stats.Add(&payload)
+		return
+	}
+
+	if t.onRequestLog != nil {
+		t.onRequestLog(rawPayload, &payload)
+		return
+	}
+
+	t.printRequestLog(os.Stdout, rawPayload, &payload)
+}
+
+// printRequestLog prints a request log in the configured output format.
+func (t *Tailer) printRequestLog(w io.Writer, rawPayload string, payload *EventPayload) {
 	if t.cfg.OutputFormat == outputFormatJSON {
-		fmt.Println(ansi.ColorizeJSON(requestLogEvent.EventPayload, false, os.Stdout))
+		fmt.Fprintln(w, ansi.ColorizeJSON(rawPayload, false, os.Stdout))
 		return
 	}
 
 	coloredStatus := ansi.ColorizeStatus(payload.Status)
 
-	url := urlForRequestID(&payload)
+	url := urlForRequestID(payload)
 	requestLink := ansi.Linkify(payload.RequestID, url, os.Stdout)
 
-	if payload.URL == "" {
-		payload.URL = "[View path in dashboard]"
+	path := payload.URL
+	if path == "" {
+		path = "[View path in dashboard]"
 	}
 
-	exampleLayout := "2006-01-02 15:04:05"
-	localTime := time.Unix(int64(payload.CreatedAt), 0).Format(exampleLayout)
+	localTime := time.Unix(int64(payload.CreatedAt), 0).Format(timeLayout)
 
 	color := ansi.Color(os.Stdout)
-	outputStr := fmt.Sprintf("%s [%d] %s %s [%s]", color.Faint(localTime), coloredStatus, payload.Method, payload.URL, requestLink)
-	fmt.Println(outputStr)
+	outputStr := fmt.Sprintf("%s [%d] %s %s [%s]", color.Faint(localTime), coloredStatus, payload.Method, path, requestLink)
+	fmt.Fprintln(w, outputStr)
 
 	errorValues := reflect.ValueOf(&payload.Error).Elem()
 	errType := errorValues.Type()
@@ -284,7 +384,7 @@ func (t *Tailer) processRequestLogEvent(msg websocket.IncomingMessage) {
 	for i := 0; i < errorValues.NumField(); i++ {
 		fieldValue := errorValues.Field(i).Interface()
 		if fieldValue != "" {
-			fmt.Printf("%s: %s\n", errType.Field(i).Name, fieldValue)
+			fmt.Fprintf(w, "%s: %s\n", errType.Field(i).Name, fieldValue)
 		}
 	}
 }
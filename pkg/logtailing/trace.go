@@ -0,0 +1,352 @@
+package logtailing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stripe/stripe-cli/pkg/ansi"
+	"github.com/stripe/stripe-cli/pkg/stripe"
+	"github.com/stripe/stripe-cli/pkg/websocket"
+)
+
+//
+// Public types
+//
+
+// Tracer shows API request logs along with the webhook events, interleaved
+// chronologically. Events are linked to the request that caused them when
+// the request was logged. The filters of the configuration only apply to
+// request logs.
+type Tracer struct {
+	tailer *Tailer
+
+	// out is where request logs and events are printed
+	out io.Writer
+
+	mu sync.Mutex
+
+	// pending holds the entries waiting for the ones that could come before
+	// them but arrive later
+	pending []*traceEntry
+	seq     int
+
+	// requests are the recent request logs by ID, to link events to them
+	requests *recentSet
+
+	// events are the IDs of the recent events, since an event may be
+	// delivered once per API version
+	events *recentSet
+}
+
+// Run sets the websocket connections for both request logs and events
+func (tr *Tracer) Run(ctx context.Context) error {
+	s := ansi.StartNewSpinner("Getting ready...", tr.tailer.cfg.Log.Out)
+
+	ctx = withSIGTERMCancel(ctx, func() {
+		log.WithFields(log.Fields{
+			"prefix": "logtailing.Tracer.Run",
+		}).Debug("Ctrl+C received, cleaning up...")
+
+		tr.flush(true)
+	})
+
+	filters, err := jsonifyFilters(tr.tailer.serverFilters)
+	if err != nil {
+		tr.tailer.cfg.Log.Fatalf("Error while converting log filters to JSON encoding: %v", err)
+	}
+
+	var readyMu sync.Mutex
+
+	readyFeatures := make(map[string]bool)
+	announced := false
+
+	ready := func(feature string) func() {
+		return func() {
+			readyMu.Lock()
+			defer readyMu.Unlock()
+
+			readyFeatures[feature] = true
+
+			switch {
+			case announced:
+				ansi.StopSpinner(s, "", tr.tailer.cfg.Log.Out)
+			case len(readyFeatures) == 2:
+				announced = true
+				ansi.StopSpinner(s, "Ready! You're now waiting to receive API request logs and webhook events (^C to quit)", tr.tailer.cfg.Log.Out)
+			}
+		}
+	}
+
+	go tr.flushPeriodically(ctx)
+
+	go tr.tailer.runSession(ctx, s, webhooksWebSocketFeature, nil, tr.processWebhookEvent, ready(webhooksWebSocketFeature))
+
+	tr.tailer.runSession(ctx, s, tr.tailer.cfg.WebSocketFeature, &filters, tr.tailer.processRequestLogEvent, ready(tr.tailer.cfg.WebSocketFeature))
+
+	log.WithFields(log.Fields{
+		"prefix": "logtailing.Tracer.Run",
+	}).Debug("Bye!")
+
+	return nil
+}
+
+//
+// Public functions
+//
+
+// NewTracer creates a new Tracer
+func NewTracer(cfg *Config) *Tracer {
+	tr := &Tracer{
+		tailer:   New(cfg),
+		out:      os.Stdout,
+		requests: newRecentSet(),
+		events:   newRecentSet(),
+	}
+
+	tr.tailer.onRequestLog = tr.addRequestLog
+
+	return tr
+}
+
+//
+// Private types
+//
+
+// traceEntry is a request log or an event waiting to be printed.
+type traceEntry struct {
+	created int
+	arrived time.Time
+	seq     int
+
+	rawPayload string
+	request    *EventPayload
+	event      *traceEvent
+}
+
+// before reports whether e should be printed before other: by creation
+// time, requests before the events they cause, then by arrival.
+func (e *traceEntry) before(other *traceEntry) bool {
+	if e.created != other.created {
+		return e.created < other.created
+	}
+
+	if (e.request != nil) != (other.request != nil) {
+		return e.request != nil
+	}
+
+	return e.seq < other.seq
+}
+
+// traceEvent is a minimal representation of a Stripe `event` object.
+type traceEvent struct {
+	Account  string          `json:"account"`
+	Created  int             `json:"created"`
+	ID       string          `json:"id"`
+	Livemode bool            `json:"livemode"`
+	Type     string          `json:"type"`
+	Request  json.RawMessage `json:"request"`
+}
+
+// requestID returns the ID of the request that caused the event, if any.
+// Depending on the API version, the request is either an object or an ID.
+func (e *traceEvent) requestID() string {
+	var request struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.Unmarshal(e.Request, &request); err == nil {
+		return request.ID
+	}
+
+	var id string
+
+	if err := json.Unmarshal(e.Request, &id); err == nil {
+		return id
+	}
+
+	return ""
+}
+
+func (e *traceEvent) urlForEventID() string {
+	return fmt.Sprintf("%s/events/%s", stripe.DashboardURL(e.Livemode, e.Account), e.ID)
+}
+
+// recentSet remembers the most recently added keys, with a value.
+type recentSet struct {
+	values map[string]interface{}
+	keys   []string
+}
+
+func newRecentSet() *recentSet {
+	return &recentSet{values: make(map[string]interface{})}
+}
+
+// add records a key and reports whether it was not already recorded.
+func (s *recentSet) add(key string, value interface{}) bool {
+	if _, ok := s.values[key]; ok {
+		return false
+	}
+
+	if len(s.keys) >= maxTracedIDs {
+		delete(s.values, s.keys[0])
+		s.keys = s.keys[1:]
+	}
+
+	s.values[key] = value
+	s.keys = append(s.keys, key)
+
+	return true
+}
+
+//
+// Private constants
+//
+
+const (
+	webhooksWebSocketFeature = "webhooks"
+
+	// interleaveDelay is how long request logs and events are held, so that
+	// the ones arriving later than others created after them are printed in
+	// order
+	interleaveDelay = 2 * time.Second
+
+	// maxTracedIDs is the number of recent requests and events remembered
+	maxTracedIDs = 1000
+)
+
+//
+// Private functions
+//
+
+func (tr *Tracer) addRequestLog(rawPayload string, payload *EventPayload) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if payload.RequestID != "" {
+		tr.requests.add(payload.RequestID, payload)
+	}
+
+	tr.addEntry(&traceEntry{created: payload.CreatedAt, rawPayload: rawPayload, request: payload})
+}
+
+func (tr *Tracer) processWebhookEvent(msg websocket.IncomingMessage) {
+	if msg.WebhookEvent == nil {
+		tr.tailer.cfg.Log.Debug("WebSocket specified for webhooks received non-webhook event")
+		return
+	}
+
+	var evt traceEvent
+	if err := json.Unmarshal([]byte(msg.WebhookEvent.EventPayload), &evt); err != nil {
+		tr.tailer.cfg.Log.Debug("Received malformed event from Stripe, ignoring")
+		return
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if !tr.events.add(evt.ID, nil) {
+		return
+	}
+
+	tr.addEntry(&traceEntry{created: evt.Created, rawPayload: msg.WebhookEvent.EventPayload, event: &evt})
+}
+
+// addEntry adds an entry to the pending ones. The caller must hold tr.mu.
+func (tr *Tracer) addEntry(entry *traceEntry) {
+	tr.seq++
+
+	entry.seq = tr.seq
+	entry.arrived = time.Now()
+
+	tr.pending = append(tr.pending, entry)
+}
+
+func (tr *Tracer) flushPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(interleaveDelay / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tr.flush(false)
+		}
+	}
+}
+
+// flush prints the pending entries in order, as long as the first one has
+// been held long enough, or all of them if all is set.
+func (tr *Tracer) flush(all bool) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	sort.SliceStable(tr.pending, func(i, j int) bool {
+		return tr.pending[i].before(tr.pending[j])
+	})
+
+	now := time.Now()
+	printed := 0
+
+	for _, entry := range tr.pending {
+		if !all && now.Sub(entry.arrived) < interleaveDelay {
+			break
+		}
+
+		tr.print(entry)
+		printed++
+	}
+
+	tr.pending = tr.pending[printed:]
+}
+
+// print prints an entry. The caller must hold tr.mu.
+func (tr *Tracer) print(entry *traceEntry) {
+	if entry.request != nil {
+		tr.tailer.printRequestLog(tr.out, entry.rawPayload, entry.request)
+		return
+	}
+
+	if tr.tailer.cfg.OutputFormat == outputFormatJSON {
+		fmt.Fprintln(tr.out, ansi.ColorizeJSON(entry.rawPayload, false, os.Stdout))
+		return
+	}
+
+	evt := entry.event
+
+	maybeConnect := ""
+	if evt.Account != "" {
+		maybeConnect = "connect "
+	}
+
+	localTime := time.Unix(int64(evt.Created), 0).Format(timeLayout)
+
+	color := ansi.Color(os.Stdout)
+	outputStr := fmt.Sprintf("%s   --> %s%s [%s]",
+		color.Faint(localTime),
+		maybeConnect,
+		ansi.Bold(evt.Type),
+		ansi.Linkify(evt.ID, evt.urlForEventID(), os.Stdout),
+	)
+
+	if requestID := evt.requestID(); requestID != "" {
+		cause := requestID
+
+		if value, ok := tr.requests.values[requestID]; ok {
+			request := value.(*EventPayload)
+			cause = fmt.Sprintf("%s %s [%s]", request.Method, request.URL, requestID)
+		}
+
+		outputStr += " " + color.Faint("(caused by "+cause+")").String()
+	}
+
+	fmt.Fprintln(tr.out, outputStr)
+}
@@ -0,0 +1,80 @@
+package logtailing
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/websocket"
+)
+
+func webhookMessage(payload string) websocket.IncomingMessage {
+	return websocket.IncomingMessage{
+		WebhookEvent: &websocket.WebhookEvent{EventPayload: payload},
+	}
+}
+
+func TestTracerInterleavesAndLinks(t *testing.T) {
+	var out bytes.Buffer
+
+	tr := NewTracer(&Config{})
+	tr.out = &out
+
+	// The event arrives before the request that caused it, and twice
+	tr.processWebhookEvent(webhookMessage(`{"id":"evt_1","type":"customer.created","created":1600000001,"request":{"id":"req_1"}}`))
+	tr.processWebhookEvent(webhookMessage(`{"id":"evt_1","type":"customer.created","created":1600000001,"request":{"id":"req_1"}}`))
+	tr.tailer.processPayload(`{"created_at":1600000001,"method":"POST","request_id":"req_1","status":200,"url":"/v1/customers"}`)
+	tr.tailer.processPayload(`{"created_at":1600000000,"method":"GET","request_id":"req_0","status":200,"url":"/v1/charges"}`)
+
+	// Events from older API versions reference requests by ID, and not all
+	// events are caused by a request
+	tr.processWebhookEvent(webhookMessage(`{"id":"evt_2","type":"charge.updated","created":1600000002,"request":"req_9"}`))
+	tr.processWebhookEvent(webhookMessage(`{"id":"evt_3","type":"invoice.created","created":1600000003,"request":{"id":null}}`))
+
+	tr.flush(true)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 5)
+
+	require.Contains(t, lines[0], "[200] GET /v1/charges [req_0]")
+	require.Contains(t, lines[1], "[200] POST /v1/customers [req_1]")
+	require.Contains(t, lines[2], "--> customer.created [evt_1] (caused by POST /v1/customers [req_1])")
+	require.Contains(t, lines[3], "--> charge.updated [evt_2] (caused by req_9)")
+	require.Contains(t, lines[4], "--> invoice.created [evt_3]")
+	require.NotContains(t, lines[4], "caused by")
+}
+
+func TestTracerHoldsRecentEntries(t *testing.T) {
+	var out bytes.Buffer
+
+	tr := NewTracer(&Config{})
+	tr.out = &out
+
+	tr.tailer.processPayload(`{"created_at":1600000000,"method":"GET","request_id":"req_0","status":200,"url":"/v1/charges"}`)
+	tr.processWebhookEvent(webhookMessage(`{"id":"evt_1","type":"customer.created","created":1600000001}`))
+
+	tr.pending[0].arrived = time.Now().Add(-interleaveDelay)
+
+	tr.flush(false)
+
+	require.Contains(t, out.String(), "req_0")
+	require.NotContains(t, out.String(), "evt_1")
+	require.Len(t, tr.pending, 1)
+}
+
+func TestRecentSet(t *testing.T) {
+	s := newRecentSet()
+
+	for i := 0; i < maxTracedIDs+1; i++ {
+		require.True(t, s.add(strings.Repeat("x", i), i))
+	}
+
+	require.False(t, s.add("xx", 0))
+	require.Len(t, s.values, maxTracedIDs)
+
+	_, ok := s.values[""]
+	require.False(t, ok)
+}
@@ -0,0 +1,131 @@
+package logtailing
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//
+// Public functions
+//
+
+// View prints the request logs saved in a file by a previous tailing session
+// (see Config.OutputFile), with the same format and filters as live
+// tailing. All the filters are applied locally, and the ones that rely on
+// information missing from request logs (account, IP address and source) are
+// ignored. If Config.Stats is set, their summary is printed instead.
+func (t *Tailer) View(r io.Reader) error {
+	t.localFilters = t.cfg.Filters
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSavedRequestLogSize)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		t.processPayload(line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if t.cfg.Stats != nil {
+		return t.cfg.Stats.Render(os.Stdout)
+	}
+
+	return nil
+}
+
+//
+// Private constants
+//
+
+// maxSavedRequestLogSize is the maximum size of a line in a saved file
+const maxSavedRequestLogSize = 1024 * 1024
+
+//
+// Private functions
+//
+
+// record writes a request log to the output file, as a line of JSON.
+func (t *Tailer) record(rawPayload string) error {
+	var buf bytes.Buffer
+
+	if err := json.Compact(&buf, []byte(rawPayload)); err != nil {
+		return err
+	}
+
+	buf.WriteByte('\n')
+
+	t.outputMu.Lock()
+	defer t.outputMu.Unlock()
+
+	_, err := t.cfg.OutputFile.Write(buf.Bytes())
+
+	return err
+}
+
+// matchesLogFilters reports whether a request log matches the filters that
+// can be evaluated locally.
+func matchesLogFilters(filters *LogFilters, payload *EventPayload) bool {
+	if len(filters.FilterHTTPMethod) > 0 && !containsFold(filters.FilterHTTPMethod, payload.Method) {
+		return false
+	}
+
+	if len(filters.FilterRequestPath) > 0 && !matchesRequestPath(filters.FilterRequestPath, payload.URL) {
+		return false
+	}
+
+	status := strconv.Itoa(payload.Status)
+
+	if len(filters.FilterStatusCode) > 0 && !containsFold(filters.FilterStatusCode, status) {
+		return false
+	}
+
+	if len(filters.FilterStatusCodeType) > 0 {
+		matched := false
+
+		// Types are either written as 4XX, or converted to 400 for Stripe
+		for _, codeType := range filters.FilterStatusCodeType {
+			if codeType != "" && len(status) == 3 && codeType[0] == status[0] {
+				matched = true
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(filters.FilterRequestStatus) > 0 {
+		requestStatus := "FAILED"
+		if payload.Status == 200 || payload.Status == 201 || payload.Status == 202 {
+			requestStatus = "SUCCEEDED"
+		}
+
+		if !containsFold(filters.FilterRequestStatus, requestStatus) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
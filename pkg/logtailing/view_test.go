@@ -0,0 +1,48 @@
+package logtailing
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	var output bytes.Buffer
+
+	tailer := New(&Config{OutputFile: &output})
+
+	tailer.processPayload("{\n  \"method\": \"POST\",\n  \"status\": 200,\n  \"url\": \"/v1/customers\"\n}")
+	tailer.processPayload(`{"method":"POST","status":200,"url":"/v1/stripecli/sessions"}`)
+
+	require.Equal(t, "{\"method\":\"POST\",\"status\":200,\"url\":\"/v1/customers\"}\n", output.String())
+}
+
+func TestViewRecordsSavedLogs(t *testing.T) {
+	var output bytes.Buffer
+
+	tailer := New(&Config{OutputFile: &output})
+
+	saved := "{\"request_id\":\"req_1\"}\n\n{\"request_id\":\"req_2\"}\n"
+	require.NoError(t, tailer.View(strings.NewReader(saved)))
+
+	require.Equal(t, "{\"request_id\":\"req_1\"}\n{\"request_id\":\"req_2\"}\n", output.String())
+}
+
+func TestMatchesLogFilters(t *testing.T) {
+	payload := &EventPayload{Method: "POST", Status: 402, URL: "/v1/payment_intents/pi_123/confirm"}
+
+	require.True(t, matchesLogFilters(&LogFilters{}, payload))
+	require.True(t, matchesLogFilters(&LogFilters{FilterHTTPMethod: []string{"get", "post"}}, payload))
+	require.False(t, matchesLogFilters(&LogFilters{FilterHTTPMethod: []string{"GET"}}, payload))
+	require.True(t, matchesLogFilters(&LogFilters{FilterRequestPath: []string{"/v1/payment_intents/*/confirm"}}, payload))
+	require.False(t, matchesLogFilters(&LogFilters{FilterRequestPath: []string{"/v1/payment_intents"}}, payload))
+	require.True(t, matchesLogFilters(&LogFilters{FilterStatusCode: []string{"402"}}, payload))
+	require.False(t, matchesLogFilters(&LogFilters{FilterStatusCode: []string{"400"}}, payload))
+	require.True(t, matchesLogFilters(&LogFilters{FilterStatusCodeType: []string{"4XX"}}, payload))
+	require.True(t, matchesLogFilters(&LogFilters{FilterStatusCodeType: []string{"200", "400"}}, payload))
+	require.False(t, matchesLogFilters(&LogFilters{FilterStatusCodeType: []string{"5XX"}}, payload))
+	require.True(t, matchesLogFilters(&LogFilters{FilterRequestStatus: []string{"FAILED"}}, payload))
+	require.False(t, matchesLogFilters(&LogFilters{FilterRequestStatus: []string{"SUCCEEDED"}}, payload))
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stripe/stripe-cli/pkg/webhooks"
+	"github.com/stripe/stripe-cli/pkg/websocket"
+)
+
+//
+// Private constants
+//
+
+const (
+	// seenWindow is how long, in seconds before the most recent event
+	// received, the IDs of the events received are remembered
+	seenWindow = 60
+
+	backfillUserAgent = "Stripe/1.0 (+https://stripe.com/docs/webhooks)"
+)
+
+//
+// Private functions
+//
+
+// recordSeen remembers that an event was received, so that it is not
+// backfilled after a disconnection.
+func (p *Proxy) recordSeen(evt *stripeEvent) {
+	created := int64(evt.Created)
+
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+
+	if created > p.lastCreated {
+		p.lastCreated = created
+
+		for id, t := range p.seen {
+			if t < created-seenWindow {
+				delete(p.seen, id)
+			}
+		}
+	}
+
+	p.seen[evt.ID] = created
+}
+
+// wasSeen reports whether an event was received recently.
+func (p *Proxy) wasSeen(id string) bool {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+
+	_, ok := p.seen[id]
+
+	return ok
+}
+
+// backfill forwards events fetched from the API as if they had been
+// delivered by Stripe, signed with the session's secret. Their responses are
+// not reported to Stripe.
+func (p *Proxy) backfill(events []json.RawMessage, source eventSource) {
+	secret, _ := p.sessionSecret.Load().(string)
+
+	for _, raw := range events {
+		// Format the payload like the ones delivered by Stripe
+		var payload bytes.Buffer
+		if err := json.Indent(&payload, raw, "", "  "); err != nil {
+			continue
+		}
+
+		headers := map[string]string{
+			"Content-Type": "application/json; charset=utf-8",
+			"User-Agent":   backfillUserAgent,
+		}
+
+		if secret != "" {
+			headers[webhooks.SignatureHeader] = webhooks.GenerateSignatureHeader(time.Now(), payload.Bytes(), secret)
+		}
+
+		webhookID := fmt.Sprintf("we_backfill_%d", atomic.AddInt32(&p.backfillCount, 1))
+
+		p.cfg.Log.WithFields(log.Fields{
+			"prefix":     "proxy.Proxy.backfill",
+			"webhook_id": webhookID,
+		}).Debugf("Backfilling event")
+
+		p.handleWebhookEvent(&websocket.WebhookEvent{
+			EventPayload: payload.String(),
+			HTTPHeaders:  headers,
+			Type:         "webhook_event",
+			WebhookID:    webhookID,
+		}, source)
+	}
+}
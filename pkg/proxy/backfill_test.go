@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/webhooks"
+	"github.com/stripe/stripe-cli/pkg/websocket"
+)
+
+func TestBackfill(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Events are listed from the last one received
+		require.Equal(t, "1000", r.URL.Query().Get("created[gte]"))
+
+		fmt.Fprint(w, `{
+  "data": [
+    {"id": "evt_3", "type": "customer.updated", "created": 1030, "data": {"object": {"id": "cus_123"}}},
+    {"id": "evt_2", "type": "charge.succeeded", "created": 1020, "data": {"object": {"id": "cus_123"}}},
+    {"id": "evt_1", "type": "customer.created", "created": 1010, "data": {"object": {"id": "cus_123"}}},
+    {"id": "evt_0", "type": "customer.created", "created": 1000, "data": {"object": {"id": "cus_123"}}}
+  ],
+  "has_more": false
+}`)
+	}))
+	defer api.Close()
+
+	var mu sync.Mutex
+
+	received := make([]string, 0)
+
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		_, err = webhooks.VerifySignatureHeader(body, r.Header.Get(webhooks.SignatureHeader), "whsec_123", webhooks.DefaultTolerance, time.Now())
+		require.NoError(t, err)
+
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+	}))
+	defer endpoint.Close()
+
+	observer := &recordingObserver{}
+
+	p, err := New(&Config{
+		Key:        "sk_test_123",
+		APIBaseURL: api.URL,
+		EndpointRoutes: []EndpointRoute{
+			{URL: endpoint.URL, EventTypes: []string{"customer.*"}},
+		},
+		Backfill:        true,
+		Observer:        observer,
+		OrderedDelivery: true,
+	}, []string{"customer.*"})
+	require.NoError(t, err)
+
+	p.sessionSecret.Store("whsec_123")
+
+	// evt_0 was received before the disconnection
+	payload := `{"id": "evt_0", "type": "customer.created", "created": 1000, "data": {"object": {"id": "cus_123"}}}`
+	p.handleWebhookEvent(&websocket.WebhookEvent{
+		EventPayload: payload,
+		HTTPHeaders:  map[string]string{webhooks.SignatureHeader: webhooks.GenerateSignatureHeader(time.Now(), []byte(payload), "whsec_123")},
+		WebhookID:    "wh_0",
+	}, sourceStripe)
+
+	p.processConnectionChange(false, errors.New("EOF"))
+	require.True(t, p.reconnected())
+
+	p.dispatcher.close()
+	require.Equal(t, 0, p.dispatcher.wait(time.Second))
+
+	// Missed events about the same object are forwarded oldest first, formatted like the ones
+	// delivered by Stripe
+	require.Len(t, received, 3)
+	require.Contains(t, received[1], `"id": "evt_1"`)
+	require.True(t, strings.HasPrefix(received[1], "{\n  \"id\""))
+	require.Contains(t, received[2], `"id": "evt_3"`)
+
+	require.Len(t, observer.events, 3)
+	require.False(t, observer.events[0].Backfilled)
+	require.True(t, observer.events[1].Backfilled)
+	require.Equal(t, "evt_1", observer.events[1].EventID)
+	require.True(t, observer.events[2].Backfilled)
+
+	require.Equal(t, 2, observer.statuses[1].MissedEvents)
+}
+
+func TestRecordSeen(t *testing.T) {
+	p, err := New(&Config{}, []string{"*"})
+	require.NoError(t, err)
+
+	p.recordSeen(&stripeEvent{ID: "evt_1", Created: 1000})
+	p.recordSeen(&stripeEvent{ID: "evt_2", Created: 1050})
+	require.True(t, p.wasSeen("evt_1"))
+
+	// Events long before the most recent one are forgotten
+	p.recordSeen(&stripeEvent{ID: "evt_3", Created: 1100})
+	require.False(t, p.wasSeen("evt_1"))
+	require.True(t, p.wasSeen("evt_2"))
+	require.True(t, p.wasSeen("evt_3"))
+}
+
+func TestBackfillAPIVersions(t *testing.T) {
+	var eventID int32 = 1
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := r.Header.Get("Stripe-Version")
+		if version == "" {
+			version = "2019-05-16"
+		}
+
+		fmt.Fprintf(w, `{"data":[{"id":"evt_%d","type":"customer.created","created":1010,"api_version":"%s"}],"has_more":false}`,
+			atomic.LoadInt32(&eventID), version)
+	}))
+	defer api.Close()
+
+	unpinned := &versionRecorder{}
+	unpinnedServer := httptest.NewServer(unpinned)
+	defer unpinnedServer.Close()
+
+	pinned := &versionRecorder{}
+	pinnedServer := httptest.NewServer(pinned)
+	defer pinnedServer.Close()
+
+	var out bytes.Buffer
+
+	p, err := New(&Config{
+		Key:        "sk_test_123",
+		APIBaseURL: api.URL,
+		Log:        &log.Logger{Out: &out},
+		EndpointRoutes: []EndpointRoute{
+			{URL: unpinnedServer.URL, EventTypes: []string{"*"}},
+			{URL: pinnedServer.URL, EventTypes: []string{"*"}, APIVersion: "2019-05-16"},
+		},
+		Backfill:            true,
+		UseLatestAPIVersion: true,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	// The latest version is not known yet, so only the pinned endpoint
+	// receives the missed event
+	p.processConnectionChange(false, errors.New("EOF"))
+	require.True(t, p.reconnected())
+	require.Contains(t, out.String(), "can't be forwarded, since the latest API version is not known yet")
+
+	latest := "2020-08-27"
+	p.processWebhookEvent(websocket.IncomingMessage{
+		WebhookEvent: &websocket.WebhookEvent{
+			Endpoint:     websocket.WebhookEndpoint{APIVersion: &latest},
+			EventPayload: `{"id":"evt_2","type":"customer.created","created":1005,"api_version":"2020-08-27"}`,
+			WebhookID:    "wh_latest",
+		},
+	})
+
+	atomic.StoreInt32(&eventID, 3)
+
+	p.processConnectionChange(false, errors.New("EOF"))
+	require.True(t, p.reconnected())
+
+	p.dispatcher.close()
+	require.Equal(t, 0, p.dispatcher.wait(time.Second))
+
+	require.Equal(t, []string{"2020-08-27", "2020-08-27"}, unpinned.versions)
+	require.Equal(t, []string{"2019-05-16", "2019-05-16"}, pinned.versions)
+}
@@ -0,0 +1,180 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+//
+// Public constants
+//
+
+// DefaultMaxConcurrentDeliveries is the number of deliveries that can be in
+// flight at the same time when no limit is configured. It is high enough for
+// local endpoints to keep up with a busy account, while preventing a slow
+// endpoint from piling up an unbounded number of connections and goroutines.
+const DefaultMaxConcurrentDeliveries = 10
+
+//
+// Private types
+//
+
+// dispatcher runs event deliveries in the background, using a fixed number of
+// long-lived workers reading from a queue. Deliveries start in the order in
+// which they were dispatched. When the dispatcher is ordered, deliveries that
+// share the same key (e.g. the same endpoint and object) also run sequentially.
+// Once closed, it can wait for the deliveries queued or in flight to complete.
+type dispatcher struct {
+	ordered bool
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// ready holds the deliveries that can start as soon as a worker is
+	// available, in dispatch order.
+	ready []dispatchedFunc
+
+	// queues holds, for ordered dispatchers, the deliveries waiting for the
+	// previous delivery with the same key to return. A key is present while
+	// one of its deliveries is ready or running.
+	queues map[string][]dispatchedFunc
+
+	pending int
+	closed  bool
+
+	wg sync.WaitGroup
+}
+
+type dispatchedFunc struct {
+	key string
+	fn  func()
+}
+
+func newDispatcher(maxConcurrency int, ordered bool) *dispatcher {
+	d := &dispatcher{
+		ordered: ordered,
+		queues:  make(map[string][]dispatchedFunc),
+	}
+	d.cond = sync.NewCond(&d.mu)
+
+	if maxConcurrency <= 0 {
+		maxConcurrency = DefaultMaxConcurrentDeliveries
+	}
+
+	for i := 0; i < maxConcurrency; i++ {
+		go d.work()
+	}
+
+	return d
+}
+
+// dispatch schedules fn to run in the background. When the dispatcher is
+// ordered, fn will not start before all the functions previously dispatched
+// with the same key have returned. Functions dispatched after the dispatcher
+// is closed are dropped.
+func (d *dispatcher) dispatch(key string, fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	d.pending++
+	d.wg.Add(1)
+
+	f := dispatchedFunc{key: key, fn: fn}
+
+	if d.ordered {
+		if queue, running := d.queues[key]; running {
+			d.queues[key] = append(queue, f)
+			return
+		}
+
+		d.queues[key] = nil
+	}
+
+	d.ready = append(d.ready, f)
+	d.cond.Signal()
+}
+
+// close stops the dispatcher from accepting new functions, and returns the
+// number of functions that are queued or running. The workers exit once the
+// queued functions have run.
+func (d *dispatcher) close() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	d.cond.Broadcast()
+
+	return d.pending
+}
+
+// wait waits for the dispatched functions to return, for up to timeout. It
+// returns the number of functions that are still queued or running.
+func (d *dispatcher) wait(timeout time.Duration) int {
+	done := make(chan struct{})
+
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.pending
+}
+
+// work runs the ready functions one after the other, until the dispatcher is
+// closed and nothing is left to run.
+func (d *dispatcher) work() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		for len(d.ready) == 0 {
+			if d.closed && d.pending == 0 {
+				return
+			}
+
+			d.cond.Wait()
+		}
+
+		f := d.ready[0]
+		d.ready = d.ready[1:]
+
+		d.mu.Unlock()
+		f.fn()
+		d.mu.Lock()
+
+		d.done(f.key)
+	}
+}
+
+// done records that a function dispatched with key has returned, and makes the
+// next function queued for that key ready. It must be called with mu held.
+func (d *dispatcher) done(key string) {
+	d.pending--
+	d.wg.Done()
+
+	if d.ordered {
+		if queue := d.queues[key]; len(queue) > 0 {
+			d.ready = append(d.ready, queue[0])
+			d.queues[key] = queue[1:]
+			d.cond.Signal()
+		} else {
+			delete(d.queues, key)
+		}
+	}
+
+	if d.closed && d.pending == 0 {
+		d.cond.Broadcast()
+	}
+}
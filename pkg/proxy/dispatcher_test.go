@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherMaxConcurrency(t *testing.T) {
+	d := newDispatcher(2, false)
+
+	wg := &sync.WaitGroup{}
+	var inFlight, maxInFlight int32
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		d.dispatch("key", func() {
+			defer wg.Done()
+
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		})
+	}
+
+	wg.Wait()
+
+	require.True(t, maxInFlight <= 2)
+}
+
+func TestDispatcherStartsInDispatchOrder(t *testing.T) {
+	d := newDispatcher(1, false)
+
+	release := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	started := make([]int, 0)
+
+	wg.Add(1)
+	d.dispatch("key", func() {
+		defer wg.Done()
+		<-release
+	})
+
+	// Queued behind the blocked delivery, on different keys
+	for i := 0; i < 10; i++ {
+		i := i
+
+		wg.Add(1)
+		d.dispatch(fmt.Sprintf("key_%d", i), func() {
+			defer wg.Done()
+			started = append(started, i)
+		})
+	}
+
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, started)
+}
+
+func TestDispatcherOrdered(t *testing.T) {
+	d := newDispatcher(0, true)
+
+	wg := &sync.WaitGroup{}
+	mu := &sync.Mutex{}
+	delivered := make(map[string][]int)
+
+	for i := 0; i < 20; i++ {
+		i := i
+		key := "cus_a"
+		if i%2 == 1 {
+			key = "cus_b"
+		}
+
+		wg.Add(1)
+		d.dispatch(key, func() {
+			defer wg.Done()
+
+			// Later deliveries are faster, so they would overtake earlier ones
+			// if they ran concurrently
+			time.Sleep(time.Duration(20-i) * time.Millisecond / 4)
+
+			mu.Lock()
+			delivered[key] = append(delivered[key], i)
+			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+
+	require.Equal(t, []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18}, delivered["cus_a"])
+	require.Equal(t, []int{1, 3, 5, 7, 9, 11, 13, 15, 17, 19}, delivered["cus_b"])
+}
+
+func TestDispatcherCloseAndWait(t *testing.T) {
+	d := newDispatcher(0, false)
+
+	release := make(chan struct{})
+	var ran int32
+
+	d.dispatch("key", func() {
+		<-release
+		atomic.AddInt32(&ran, 1)
+	})
+
+	require.Equal(t, 1, d.close())
+
+	// Functions dispatched once closed are dropped
+	d.dispatch("key", func() {
+		atomic.AddInt32(&ran, 1)
+	})
+
+	require.Equal(t, 1, d.wait(10*time.Millisecond))
+
+	close(release)
+
+	require.Equal(t, 0, d.wait(time.Second))
+	require.Equal(t, int32(1), atomic.LoadInt32(&ran))
+}
@@ -2,7 +2,9 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -13,6 +15,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/stripe/stripe-cli/pkg/ansi"
+	"github.com/stripe/stripe-cli/pkg/webhooks"
 )
 
 //
@@ -25,7 +28,55 @@ type EndpointConfig struct {
 
 	Log *log.Logger
 
+	// Out is where delivery errors and retries are printed (default: os.Stdout)
+	Out io.Writer
+
 	ResponseHandler EndpointResponseHandler
+
+	AttemptHandler EndpointAttemptHandler
+
+	// Retry is the policy used when delivering an event to the endpoint fails
+	Retry RetryPolicy
+
+	// SigningSecret, if set, is used to compute a fresh `Stripe-Signature`
+	// header for every delivery instead of forwarding the one sent by Stripe
+	SigningSecret string
+
+	// APIVersion, if set, pins the endpoint to the events rendered with that
+	// API version
+	APIVersion string
+}
+
+// RetryPolicy describes how deliveries to an endpoint are retried when the
+// endpoint can't be reached or returns a server error. The zero value
+// disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of delivery attempts, including the
+	// first one. Values lower than 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. It doubles with
+	// every subsequent retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between two retries.
+	MaxBackoff time.Duration
+
+	// RetryOnServerError indicates whether 5xx responses should be retried.
+	// Connection errors are always retried.
+	RetryOnServerError bool
+}
+
+// DeliveryError is returned by Post when the endpoint could not be reached.
+type DeliveryError struct {
+	// Attempts is the number of delivery attempts that were made
+	Attempts int
+
+	Err error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
 }
 
 // EndpointResponseHandler handles a response from the endpoint.
@@ -44,6 +95,21 @@ func (f EndpointResponseHandlerFunc) ProcessResponse(evtCtx eventContext, forwar
 	f(evtCtx, forwardURL, resp)
 }
 
+// EndpointAttemptHandler is notified of every attempt to deliver an event to
+// the endpoint.
+type EndpointAttemptHandler interface {
+	ProcessAttempt(eventContext, string)
+}
+
+// EndpointAttemptHandlerFunc is an adapter to allow the use of ordinary
+// functions as attempt handlers.
+type EndpointAttemptHandlerFunc func(eventContext, string)
+
+// ProcessAttempt calls f(evtCtx, forwardURL).
+func (f EndpointAttemptHandlerFunc) ProcessAttempt(evtCtx eventContext, forwardURL string) {
+	f(evtCtx, forwardURL)
+}
+
 // EndpointClient is the client used to POST webhook requests to the local endpoint.
 type EndpointClient struct {
 	// URL the client sends POST requests to
@@ -53,42 +119,127 @@ type EndpointClient struct {
 
 	connect bool
 
-	events map[string]bool
+	events []string
 
 	// Optional configuration parameters
 	cfg *EndpointConfig
 }
 
 // SupportsEventType takes an event of a webhook and compares it to the internal
-// list of supported events
+// list of supported event patterns
 func (c *EndpointClient) SupportsEventType(connect bool, eventType string) bool {
 	if connect != c.connect {
 		return false
 	}
 
-	// Endpoint supports all events, always return true
-	if c.events["*"] || c.events[eventType] {
-		return true
-	}
+	return EventTypeMatches(c.events, eventType)
+}
 
-	return false
+// SupportsAPIVersion reports whether the endpoint accepts events rendered with
+// the given API version. Endpoints that are not pinned to a version accept
+// all of them.
+func (c *EndpointClient) SupportsAPIVersion(version string) bool {
+	return c.cfg.APIVersion == "" || c.cfg.APIVersion == version
 }
 
-// Post sends a message to the local endpoint.
-func (c *EndpointClient) Post(evtCtx eventContext, body string, headers map[string]string) error {
+// Post sends a message to the local endpoint, retrying according to the
+// endpoint's retry policy. The response handler is only called with the
+// final response. Canceling ctx interrupts the request in progress, or the
+// wait before the next attempt.
+func (c *EndpointClient) Post(ctx context.Context, evtCtx eventContext, body string, headers map[string]string) error {
 	c.cfg.Log.WithFields(log.Fields{
 		"prefix": "proxy.EndpointClient.Post",
 	}).Debug("Forwarding event to local endpoint")
 
-	req, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewBuffer([]byte(body)))
+	color := ansi.Color(c.cfg.Out)
+	backoff := c.cfg.Retry.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		evtCtx.attempts = attempt
+		c.cfg.AttemptHandler.ProcessAttempt(evtCtx, c.URL)
+
+		start := time.Now()
+		resp, err := c.post(ctx, body, headers)
+		evtCtx.latency = time.Since(start)
+
+		retriable := ctx.Err() == nil && (err != nil || (c.cfg.Retry.RetryOnServerError && resp.StatusCode >= 500))
+
+		if !retriable || attempt >= c.cfg.Retry.MaxAttempts {
+			if err != nil {
+				localTime := time.Now().Format(timeLayout)
+
+				maybeAttempts := ""
+				if attempt > 1 {
+					maybeAttempts = fmt.Sprintf(" after %d attempts", attempt)
+				}
+
+				errStr := fmt.Sprintf("%s            [%s] Failed to POST%s: %v\n",
+					color.Faint(localTime),
+					color.Red("ERROR"),
+					maybeAttempts,
+					err,
+				)
+				fmt.Fprintln(c.cfg.Out, errStr)
+
+				return &DeliveryError{Attempts: attempt, Err: err}
+			}
+
+			c.cfg.ResponseHandler.ProcessResponse(evtCtx, c.URL, resp)
+			resp.Body.Close()
+
+			return nil
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = fmt.Sprintf("received status %d", resp.StatusCode)
+			resp.Body.Close()
+		}
+
+		localTime := time.Now().Format(timeLayout)
+		fmt.Fprintf(c.cfg.Out, "%s            [%s] Failed to POST to %s (attempt %d/%d, %s), retrying in %s\n",
+			color.Faint(localTime),
+			color.Yellow("RETRY"),
+			c.URL,
+			attempt,
+			c.cfg.Retry.MaxAttempts,
+			reason,
+			backoff,
+		)
+
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = fmt.Errorf("%s, then %v", reason, ctx.Err())
+			}
+
+			return &DeliveryError{Attempts: attempt, Err: err}
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > c.cfg.Retry.MaxBackoff {
+			backoff = c.cfg.Retry.MaxBackoff
+		}
+	}
+}
+
+func (c *EndpointClient) post(ctx context.Context, body string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewBuffer([]byte(body)))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
 
+	if c.cfg.SigningSecret != "" {
+		req.Header.Set(webhooks.SignatureHeader, webhooks.GenerateSignatureHeader(time.Now(), []byte(body), c.cfg.SigningSecret))
+	}
+
 	// add custom headers
 	for k, v := range c.headers {
 		if strings.ToLower(k) == "host" {
@@ -98,26 +249,7 @@ func (c *EndpointClient) Post(evtCtx eventContext, body string, headers map[stri
 		}
 	}
 
-	resp, err := c.cfg.HTTPClient.Do(req)
-	if err != nil {
-		color := ansi.Color(os.Stdout)
-		localTime := time.Now().Format(timeLayout)
-
-		errStr := fmt.Sprintf("%s            [%s] Failed to POST: %v\n",
-			color.Faint(localTime),
-			color.Red("ERROR"),
-			err,
-		)
-		fmt.Println(errStr)
-
-		return err
-	}
-
-	defer resp.Body.Close()
-
-	c.cfg.ResponseHandler.ProcessResponse(evtCtx, c.URL, resp)
-
-	return nil
+	return c.cfg.HTTPClient.Do(req)
 }
 
 //
@@ -134,6 +266,10 @@ func NewEndpointClient(url string, headers []string, connect bool, events []stri
 		cfg.Log = &log.Logger{Out: ioutil.Discard}
 	}
 
+	if cfg.Out == nil {
+		cfg.Out = os.Stdout
+	}
+
 	if cfg.HTTPClient == nil {
 		cfg.HTTPClient = &http.Client{
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -143,15 +279,27 @@ func NewEndpointClient(url string, headers []string, connect bool, events []stri
 		}
 	}
 
+	if cfg.Retry.InitialBackoff == 0 {
+		cfg.Retry.InitialBackoff = defaultRetryInitialBackoff
+	}
+
+	if cfg.Retry.MaxBackoff == 0 {
+		cfg.Retry.MaxBackoff = defaultRetryMaxBackoff
+	}
+
 	if cfg.ResponseHandler == nil {
 		cfg.ResponseHandler = EndpointResponseHandlerFunc(func(eventContext, string, *http.Response) {})
 	}
 
+	if cfg.AttemptHandler == nil {
+		cfg.AttemptHandler = EndpointAttemptHandlerFunc(func(eventContext, string) {})
+	}
+
 	return &EndpointClient{
 		URL:     url,
 		headers: convertToMapAndSanitize(headers),
 		connect: connect,
-		events:  convertToMap(events),
+		events:  events,
 		cfg:     cfg,
 	}
 }
@@ -162,21 +310,16 @@ func NewEndpointClient(url string, headers []string, connect bool, events []stri
 
 const (
 	defaultTimeout = 30 * time.Second
+
+	defaultRetryInitialBackoff = 1 * time.Second
+
+	defaultRetryMaxBackoff = 30 * time.Second
 )
 
 //
 // Private functions
 //
 
-func convertToMap(events []string) map[string]bool {
-	eventsMap := make(map[string]bool)
-	for _, event := range events {
-		eventsMap[event] = true
-	}
-
-	return eventsMap
-}
-
 func convertToMapAndSanitize(headers []string) map[string]string {
 	reg := regexp.MustCompile("[\x00-\x1f]+")
 
@@ -1,13 +1,19 @@
 package proxy
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/webhooks"
 )
 
 func TestClientHandler(t *testing.T) {
@@ -72,7 +78,7 @@ func TestClientHandler(t *testing.T) {
 		"Stripe-Signature": "t=123,v1=hunter2",
 	}
 
-	err := client.Post(evtCtx, payload, headers)
+	err := client.Post(context.Background(), evtCtx, payload, headers)
 	require.NoError(t, err)
 
 	wg.Wait()
@@ -129,8 +135,165 @@ func TestClientHandler_Redirects(t *testing.T) {
 		"Stripe-Signature": "t=123,v1=hunter2",
 	}
 
-	err := client.Post(evtCtx, payload, headers)
+	err := client.Post(context.Background(), evtCtx, payload, headers)
 	require.NoError(t, err)
 
 	wg.Wait()
 }
+
+func TestClientHandler_RetriesServerErrors(t *testing.T) {
+	n := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "{}", string(reqBody))
+
+		n++
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer ts.Close()
+
+	rcvCtx := eventContext{}
+	rcvStatus := 0
+	client := NewEndpointClient(
+		ts.URL,
+		[]string{},
+		false,
+		[]string{"*"},
+		&EndpointConfig{
+			ResponseHandler: EndpointResponseHandlerFunc(func(evtCtx eventContext, forwardURL string, resp *http.Response) {
+				rcvCtx = evtCtx
+				rcvStatus = resp.StatusCode
+			}),
+			Retry: RetryPolicy{
+				MaxAttempts:        5,
+				InitialBackoff:     time.Millisecond,
+				RetryOnServerError: true,
+			},
+		},
+	)
+
+	err := client.Post(context.Background(), eventContext{event: &stripeEvent{ID: "evt_123"}}, "{}", map[string]string{})
+	require.NoError(t, err)
+
+	require.Equal(t, 3, n)
+	require.Equal(t, http.StatusOK, rcvStatus)
+	require.Equal(t, 3, rcvCtx.attempts)
+}
+
+func TestClientHandler_GivesUp(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	client := NewEndpointClient(
+		url,
+		[]string{},
+		false,
+		[]string{"*"},
+		&EndpointConfig{
+			ResponseHandler: EndpointResponseHandlerFunc(func(evtCtx eventContext, forwardURL string, resp *http.Response) {
+				require.FailNow(t, "Response handler should not be called")
+			}),
+			Retry: RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+			},
+		},
+	)
+
+	err := client.Post(context.Background(), eventContext{event: &stripeEvent{ID: "evt_123"}}, "{}", map[string]string{})
+	require.Error(t, err)
+
+	deliveryErr, ok := err.(*DeliveryError)
+	require.True(t, ok)
+	require.Equal(t, 3, deliveryErr.Attempts)
+}
+
+func TestClientHandler_CanceledDuringBackoff(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := NewEndpointClient(
+		ts.URL,
+		[]string{},
+		false,
+		[]string{"*"},
+		&EndpointConfig{
+			ResponseHandler: EndpointResponseHandlerFunc(func(evtCtx eventContext, forwardURL string, resp *http.Response) {
+				require.FailNow(t, "Response handler should not be called")
+			}),
+			Retry: RetryPolicy{
+				MaxAttempts:        3,
+				InitialBackoff:     time.Minute,
+				RetryOnServerError: true,
+			},
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := client.Post(ctx, eventContext{event: &stripeEvent{ID: "evt_123"}}, "{}", map[string]string{})
+	require.Less(t, int64(time.Since(start)), int64(10*time.Second))
+	require.EqualError(t, err, "received status 503, then context canceled")
+
+	deliveryErr, ok := err.(*DeliveryError)
+	require.True(t, ok)
+	require.Equal(t, 1, deliveryErr.Attempts)
+}
+
+func TestSupportsEventType(t *testing.T) {
+	client := NewEndpointClient("http://localhost", []string{}, false, []string{"invoice.*", "!invoice.upcoming"}, nil)
+
+	require.True(t, client.SupportsEventType(false, "invoice.payment_failed"))
+	require.False(t, client.SupportsEventType(false, "invoice.upcoming"))
+	require.False(t, client.SupportsEventType(false, "charge.succeeded"))
+	require.False(t, client.SupportsEventType(true, "invoice.payment_failed"))
+}
+
+func TestClientHandler_SigningSecret(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		header := r.Header.Get("Stripe-Signature")
+		require.NotEqual(t, "t=123,v1=hunter2", header)
+
+		parts := strings.Split(header, ",")
+		require.Equal(t, 2, len(parts))
+
+		timestamp, err := strconv.ParseInt(strings.TrimPrefix(parts[0], "t="), 10, 64)
+		require.NoError(t, err)
+
+		expected := webhooks.GenerateSignatureHeader(time.Unix(timestamp, 0), reqBody, "whsec_test_secret")
+		require.Equal(t, expected, header)
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := NewEndpointClient(
+		ts.URL,
+		[]string{},
+		false,
+		[]string{"*"},
+		&EndpointConfig{
+			SigningSecret: "whsec_test_secret",
+		},
+	)
+
+	headers := map[string]string{
+		"Stripe-Signature": "t=123,v1=hunter2",
+	}
+
+	err := client.Post(context.Background(), eventContext{event: &stripeEvent{ID: "evt_123"}}, `{"id":"evt_123"}`, headers)
+	require.NoError(t, err)
+}
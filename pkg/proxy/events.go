@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"path"
+	"strings"
+)
+
+//
+// Public functions
+//
+
+// EventPatternMatches reports whether eventType matches a single event
+// pattern. Patterns are either a literal event type, `*`, or a shell-style
+// glob such as `invoice.*` or `customer.subscription.*`. A leading `!`
+// (negation) is ignored here; see EventTypeMatches.
+func EventPatternMatches(pattern string, eventType string) bool {
+	pattern = strings.TrimPrefix(pattern, "!")
+
+	if pattern == "*" || pattern == eventType {
+		return true
+	}
+
+	matched, err := path.Match(pattern, eventType)
+
+	return err == nil && matched
+}
+
+// EventTypeMatches reports whether eventType is selected by a list of event
+// patterns. An event type is selected when it matches at least one pattern
+// and none of the negated (`!`-prefixed) patterns. If the list only contains
+// negated patterns, every other event type is selected.
+func EventTypeMatches(patterns []string, eventType string) bool {
+	included := false
+	hasInclusions := false
+
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			if EventPatternMatches(pattern, eventType) {
+				return false
+			}
+
+			continue
+		}
+
+		hasInclusions = true
+
+		if EventPatternMatches(pattern, eventType) {
+			included = true
+		}
+	}
+
+	if !hasInclusions {
+		return len(patterns) > 0
+	}
+
+	return included
+}
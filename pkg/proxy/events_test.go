@@ -0,0 +1,35 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventPatternMatches(t *testing.T) {
+	require.True(t, EventPatternMatches("*", "charge.succeeded"))
+	require.True(t, EventPatternMatches("charge.succeeded", "charge.succeeded"))
+	require.True(t, EventPatternMatches("invoice.*", "invoice.payment_failed"))
+	require.True(t, EventPatternMatches("customer.subscription.*", "customer.subscription.created"))
+	require.True(t, EventPatternMatches("customer.*", "customer.subscription.created"))
+	require.True(t, EventPatternMatches("!charge.updated", "charge.updated"))
+
+	require.False(t, EventPatternMatches("invoice.*", "invoiceitem.created"))
+	require.False(t, EventPatternMatches("charge.succeeded", "charge.failed"))
+	require.False(t, EventPatternMatches("[invalid", "[invalid.event"))
+}
+
+func TestEventTypeMatches(t *testing.T) {
+	require.True(t, EventTypeMatches([]string{"*"}, "charge.updated"))
+	require.True(t, EventTypeMatches([]string{"invoice.*", "charge.*"}, "charge.updated"))
+	require.False(t, EventTypeMatches([]string{"invoice.*"}, "charge.updated"))
+
+	require.False(t, EventTypeMatches([]string{"charge.*", "!charge.updated"}, "charge.updated"))
+	require.True(t, EventTypeMatches([]string{"charge.*", "!charge.updated"}, "charge.succeeded"))
+
+	// Only negations selects everything else
+	require.False(t, EventTypeMatches([]string{"!charge.updated"}, "charge.updated"))
+	require.True(t, EventTypeMatches([]string{"!charge.updated"}, "customer.created"))
+
+	require.False(t, EventTypeMatches([]string{}, "customer.created"))
+}
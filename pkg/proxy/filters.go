@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//
+// Public types
+//
+
+// PayloadFilter is a condition on a field of the event payload, e.g.
+//
+//	data.object.metadata.order_id exists
+//	data.object.amount > 1000
+//	account == acct_123
+//
+// Fields are designated with the same dot-separated paths as transform rules.
+// The supported operators are exists, !exists, ==, !=, >, >=, < and <=.
+// Values may be quoted.
+type PayloadFilter struct {
+	path     []string
+	operator string
+	value    string
+}
+
+// Matches reports whether payload, a decoded JSON event, satisfies the
+// filter.
+func (f *PayloadFilter) Matches(payload interface{}) bool {
+	actual, found := getPath(payload, f.path)
+
+	switch f.operator {
+	case "exists":
+		return found && actual != nil
+	case "!exists":
+		return !found || actual == nil
+	}
+
+	if !found {
+		return f.operator == "!="
+	}
+
+	actualStr := filterValueString(actual)
+
+	switch f.operator {
+	case "==", "!=":
+		equal := actualStr == f.value
+
+		// Compare numbers by value, e.g. so that 1000 == 1000.0
+		if a, b, ok := parseFloats(actualStr, f.value); ok {
+			equal = a == b
+		}
+
+		return equal == (f.operator == "==")
+	default:
+		a, b, ok := parseFloats(actualStr, f.value)
+		if !ok {
+			return false
+		}
+
+		switch f.operator {
+		case ">":
+			return a > b
+		case ">=":
+			return a >= b
+		case "<":
+			return a < b
+		default:
+			return a <= b
+		}
+	}
+}
+
+// String returns the expression of the filter.
+func (f *PayloadFilter) String() string {
+	if f.operator == "exists" || f.operator == "!exists" {
+		return fmt.Sprintf("%s %s", strings.Join(f.path, "."), f.operator)
+	}
+
+	return fmt.Sprintf("%s %s %s", strings.Join(f.path, "."), f.operator, f.value)
+}
+
+//
+// Public functions
+//
+
+// ParsePayloadFilter parses a filter expression such as
+// `data.object.amount > 1000`.
+func ParsePayloadFilter(expr string) (*PayloadFilter, error) {
+	if m := existsFilterRegexp.FindStringSubmatch(expr); m != nil {
+		operator := "exists"
+		if m[2] != "exists" {
+			operator = "!exists"
+		}
+
+		return &PayloadFilter{path: splitPath(m[1]), operator: operator}, nil
+	}
+
+	if m := comparisonFilterRegexp.FindStringSubmatch(expr); m != nil {
+		return &PayloadFilter{path: splitPath(m[1]), operator: m[2], value: unquote(m[3])}, nil
+	}
+
+	return nil, fmt.Errorf("invalid filter %q: expected `<field> exists` or `<field> <operator> <value>` with one of ==, !=, >, >=, <, <=", expr)
+}
+
+//
+// Private variables
+//
+
+var (
+	existsFilterRegexp     = regexp.MustCompile(`^\s*([^\s=!<>]+)\s+(exists|!exists|not exists)\s*$`)
+	comparisonFilterRegexp = regexp.MustCompile(`^\s*([^\s=!<>]+)\s*(==|!=|>=|<=|>|<)\s*(.+?)\s*$`)
+)
+
+//
+// Private functions
+//
+
+// matchesFilters reports whether the payload satisfies all the configured
+// filters.
+func (p *Proxy) matchesFilters(payload string) bool {
+	if len(p.cfg.Filters) == 0 {
+		return true
+	}
+
+	var decoded interface{}
+
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&decoded); err != nil {
+		return false
+	}
+
+	for _, filter := range p.cfg.Filters {
+		if !filter.Matches(decoded) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func filterValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	case json.Number, bool:
+		return fmt.Sprint(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+func parseFloats(a, b string) (float64, float64, bool) {
+	x, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	y, err := strconv.ParseFloat(b, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return x, y, true
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decodeFilterTestPayload(t *testing.T, payload string) interface{} {
+	var decoded interface{}
+
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&decoded))
+
+	return decoded
+}
+
+func TestPayloadFilter(t *testing.T) {
+	payload := decodeFilterTestPayload(t, `{
+  "account": "acct_123",
+  "livemode": false,
+  "data": {
+    "object": {
+      "amount": 1500,
+      "description": "Order for Jenny Rosen",
+      "metadata": {"order_id": "6735", "developer": "jenny"},
+      "customer": null
+    }
+  }
+}`)
+
+	tests := map[string]bool{
+		"data.object.metadata.order_id exists":               true,
+		"data.object.metadata.missing exists":                false,
+		"data.object.metadata.missing !exists":               true,
+		"data.object.customer exists":                        false,
+		"data.object.customer not exists":                    true,
+		"data.object.amount > 1000":                          true,
+		"data.object.amount>2000":                            false,
+		"data.object.amount >= 1500":                         true,
+		"data.object.amount < 1500":                          false,
+		"data.object.amount <= 1500.0":                       true,
+		"data.object.amount == 1500.00":                      true,
+		"data.object.amount > abc":                           false,
+		"account == acct_123":                                true,
+		"account != acct_123":                                false,
+		"account == 'acct_123'":                              true,
+		"livemode == false":                                  true,
+		`data.object.description == "Order for Jenny Rosen"`: true,
+		"data.object.metadata.developer == alice":            false,
+		"data.object.metadata.missing != alice":              true,
+		"data.object.metadata.missing == alice":              false,
+	}
+
+	for expr, expected := range tests {
+		filter, err := ParsePayloadFilter(expr)
+		require.NoError(t, err, expr)
+		require.Equal(t, expected, filter.Matches(payload), expr)
+	}
+}
+
+func TestParsePayloadFilterInvalid(t *testing.T) {
+	for _, expr := range []string{"", "account", "account is acct_123", "== acct_123"} {
+		_, err := ParsePayloadFilter(expr)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestPayloadFilterString(t *testing.T) {
+	filter, err := ParsePayloadFilter(`  data.object.amount>1000 `)
+	require.NoError(t, err)
+	require.Equal(t, "data.object.amount > 1000", filter.String())
+
+	filter, err = ParsePayloadFilter("account not exists")
+	require.NoError(t, err)
+	require.Equal(t, "account !exists", filter.String())
+}
+
+func TestFiltersAppliedToEvents(t *testing.T) {
+	received := make(chan string, 2)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	filter, err := ParsePayloadFilter("data.object.metadata.developer == jenny")
+	require.NoError(t, err)
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"*"}},
+		},
+		Filters: []*PayloadFilter{filter},
+		Out:     ioutil.Discard,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	mine := `{"id":"evt_1","type":"customer.created","data":{"object":{"metadata":{"developer":"jenny"}}}}`
+	theirs := `{"id":"evt_2","type":"customer.created","data":{"object":{"metadata":{"developer":"alice"}}}}`
+
+	require.Equal(t, http.StatusOK, injectEvent(p, theirs))
+	require.Equal(t, http.StatusAccepted, injectEvent(p, mine))
+	require.Equal(t, 0, p.dispatcher.wait(5*time.Second))
+
+	require.Len(t, received, 1)
+	require.Equal(t, mine, <-received)
+}
@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stripe/stripe-cli/pkg/webhooks"
+	"github.com/stripe/stripe-cli/pkg/websocket"
+)
+
+// The local ingress accepts event JSON POSTed to a local HTTP port and runs it
+// through the same filtering, printing and forwarding pipeline as the events
+// delivered by Stripe. It lets tests inject synthetic events without any
+// network access. For example:
+//
+//	curl -d @event.json http://localhost:9000
+
+//
+// Private constants
+//
+
+const (
+	maxIngressBodySize = 1 << 20
+
+	ingressUserAgent = "Stripe/1.0 (+https://stripe.com/docs/webhooks)"
+
+	errIngressStopping = "stripe listen is shutting down and no longer accepts events"
+)
+
+//
+// Private functions
+//
+
+// listenLocalIngress starts accepting events on the configured local address,
+// until ctx is done.
+func (p *Proxy) listenLocalIngress(ctx context.Context) (net.Addr, error) {
+	listener, err := net.Listen("tcp", p.cfg.LocalAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{Handler: http.HandlerFunc(p.handleIngressRequest)}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		server.Shutdown(shutdownCtx) //nolint:errcheck
+	}()
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			p.cfg.Log.Errorf("Local ingress stopped: %v", err)
+		}
+	}()
+
+	return listener.Addr(), nil
+}
+
+func (p *Proxy) handleIngressRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeIngressResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "Events must be sent with POST"})
+
+		return
+	}
+
+	if atomic.LoadInt32(&p.stopping) == 1 {
+		writeIngressResponse(w, http.StatusServiceUnavailable, map[string]string{"error": errIngressStopping})
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxIngressBodySize))
+	if err != nil {
+		writeIngressResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var evt stripeEvent
+	if err := json.Unmarshal(body, &evt); err != nil || evt.Type == "" {
+		writeIngressResponse(w, http.StatusBadRequest, map[string]string{"error": "The request body must be an event, with at least a type"})
+		return
+	}
+
+	webhookID := fmt.Sprintf("we_local_%d", atomic.AddInt32(&p.ingressCount, 1))
+
+	headers := map[string]string{
+		"Content-Type": "application/json; charset=utf-8",
+		"User-Agent":   ingressUserAgent,
+	}
+
+	// Events are signed when forwarded if the route has a signing secret.
+	// Otherwise, pass through any signature set by the sender.
+	if signature := r.Header.Get(webhooks.SignatureHeader); signature != "" {
+		headers[webhooks.SignatureHeader] = signature
+	}
+
+	p.cfg.Log.WithFields(log.Fields{
+		"prefix":     "proxy.Proxy.handleIngressRequest",
+		"webhook_id": webhookID,
+	}).Debugf("Received event on local ingress")
+
+	outcome := p.handleWebhookEvent(&websocket.WebhookEvent{
+		EventPayload: string(body),
+		HTTPHeaders:  headers,
+		Type:         "webhook_event",
+		WebhookID:    webhookID,
+	}, sourceIngress)
+
+	switch outcome {
+	case eventFiltered:
+		// The event was handled, so there's no point in sending it again
+		writeIngressResponse(w, http.StatusOK, map[string]string{
+			"webhook_id": webhookID,
+			"filtered":   "The event doesn't match the --events or --filter flags of stripe listen, so it was not forwarded",
+		})
+	case eventDropped:
+		if atomic.LoadInt32(&p.stopping) == 1 {
+			writeIngressResponse(w, http.StatusServiceUnavailable, map[string]string{"error": errIngressStopping})
+			return
+		}
+
+		writeIngressResponse(w, http.StatusUnprocessableEntity, map[string]string{"error": "The event could not be forwarded, see the output of stripe listen"})
+	default:
+		writeIngressResponse(w, http.StatusAccepted, map[string]string{"webhook_id": webhookID})
+	}
+}
+
+func writeIngressResponse(w http.ResponseWriter, status int, body map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(body) //nolint:errcheck
+}
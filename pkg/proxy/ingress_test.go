@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalIngress(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan string, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		received <- r
+		bodies <- string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"customer.*"}},
+		},
+		LocalAddr: "localhost:0",
+		Out:       &out,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, err := p.listenLocalIngress(ctx)
+	require.NoError(t, err)
+
+	ingressURL := "http://" + addr.String()
+
+	req, err := http.NewRequest(http.MethodPost, ingressURL, strings.NewReader(`{"id":"evt_123","type":"customer.created"}`))
+	require.NoError(t, err)
+	req.Header.Set("Stripe-Signature", "t=123,v1=hunter2")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case r := <-received:
+		require.Equal(t, "t=123,v1=hunter2", r.Header.Get("Stripe-Signature"))
+		require.Equal(t, `{"id":"evt_123","type":"customer.created"}`, <-bodies)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not forwarded")
+	}
+
+	// Malformed events are rejected
+	resp, err = http.Post(ingressURL, "application/json", strings.NewReader(`{"id":"evt_123"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(ingressURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestLocalIngressFiltered(t *testing.T) {
+	p, err := New(&Config{LocalAddr: "localhost:0"}, []string{"customer.*"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, err := p.listenLocalIngress(ctx)
+	require.NoError(t, err)
+
+	ingressURL := "http://" + addr.String()
+
+	// Events that don't match --events are reported as filtered out
+	resp, err := http.Post(ingressURL, "application/json", strings.NewReader(`{"id":"evt_123","type":"charge.succeeded"}`))
+	require.NoError(t, err)
+
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "we_local_1", body["webhook_id"])
+	require.Contains(t, body["filtered"], "--events or --filter")
+}
+
+func TestRunLocalOnly(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	observer := &recordingObserver{}
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"*"}},
+		},
+		LocalAddr: "localhost:0",
+		LocalOnly: true,
+		Out:       ioutil.Discard,
+		Observer:  observer,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- p.Run(ctx)
+	}()
+
+	select {
+	case <-p.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("proxy did not become ready")
+	}
+
+	resp, err := http.Post("http://"+p.LocalAddr().String(), "application/json", strings.NewReader(`{"id":"evt_123","type":"customer.created"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Run waits for the delivery in progress before returning
+	cancel()
+	require.NoError(t, <-runErr)
+
+	require.Len(t, observer.events, 1)
+	require.Len(t, observer.responses, 1)
+	require.Equal(t, http.StatusOK, observer.responses[0].Status)
+}
@@ -0,0 +1,233 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/stripe/stripe-cli/pkg/websocket"
+)
+
+//
+// Public types
+//
+
+// Journal appends the webhook events received by the proxy, along with the
+// responses of the local endpoints they were forwarded to, to a file. Each
+// line of the file is a single JSON record.
+type Journal struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// JournalEntry is a webhook event read back from a journal, along with all
+// the endpoint responses that were recorded for it.
+type JournalEntry struct {
+	ReceivedAt            time.Time
+	WebhookID             string
+	WebhookConversationID string
+	APIVersion            *string
+	EventPayload          string
+	HTTPHeaders           map[string]string
+
+	// EventID and EventType are extracted from the event payload
+	EventID   string
+	EventType string
+
+	Responses []JournalResponse
+}
+
+// JournalResponse is the outcome of forwarding a journaled event to a local
+// endpoint.
+type JournalResponse struct {
+	Time       time.Time
+	ForwardURL string
+	Status     int
+	Body       string
+
+	// Error is set when the endpoint could not be reached at all
+	Error string
+}
+
+// RecordEvent appends a webhook event to the journal.
+func (j *Journal) RecordEvent(evt *websocket.WebhookEvent) error {
+	return j.write(&journalRecord{
+		Type:                  journalRecordEvent,
+		Time:                  time.Now(),
+		WebhookID:             evt.WebhookID,
+		WebhookConversationID: evt.WebhookConversationID,
+		APIVersion:            evt.Endpoint.APIVersion,
+		EventPayload:          evt.EventPayload,
+		HTTPHeaders:           evt.HTTPHeaders,
+	})
+}
+
+// RecordResponse appends the response of a local endpoint to the journal.
+func (j *Journal) RecordResponse(webhookID string, forwardURL string, status int, body string) error {
+	return j.write(&journalRecord{
+		Type:       journalRecordResponse,
+		Time:       time.Now(),
+		WebhookID:  webhookID,
+		ForwardURL: forwardURL,
+		Status:     status,
+		Body:       body,
+	})
+}
+
+// RecordError appends a failed attempt to reach a local endpoint to the
+// journal.
+func (j *Journal) RecordError(webhookID string, forwardURL string, err error) error {
+	return j.write(&journalRecord{
+		Type:       journalRecordResponse,
+		Time:       time.Now(),
+		WebhookID:  webhookID,
+		ForwardURL: forwardURL,
+		Error:      err.Error(),
+	})
+}
+
+// Close closes the underlying file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.w.Close()
+}
+
+func (j *Journal) write(record *journalRecord) error {
+	buf, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err = j.w.Write(append(buf, '\n'))
+
+	return err
+}
+
+//
+// Public functions
+//
+
+// OpenJournal opens the journal file at path, creating it if necessary.
+// New records are appended to any existing content.
+func OpenJournal(path string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Journal{w: f}, nil
+}
+
+// ReadJournal reads all the entries of the journal file at path, in the order
+// in which the events were received.
+func ReadJournal(path string) ([]*JournalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readJournal(f)
+}
+
+//
+// Private types
+//
+
+type journalRecord struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+
+	WebhookID             string            `json:"webhook_id"`
+	WebhookConversationID string            `json:"webhook_conversation_id,omitempty"`
+	APIVersion            *string           `json:"api_version,omitempty"`
+	EventPayload          string            `json:"event_payload,omitempty"`
+	HTTPHeaders           map[string]string `json:"http_headers,omitempty"`
+
+	ForwardURL string `json:"forward_url,omitempty"`
+	Status     int    `json:"status,omitempty"`
+	Body       string `json:"body,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+//
+// Private constants
+//
+
+const (
+	journalRecordEvent    = "webhook_event"
+	journalRecordResponse = "endpoint_response"
+
+	// Event payloads can be much larger than bufio.Scanner's default limit
+	maxJournalLineSize = 10 * 1024 * 1024
+)
+
+//
+// Private functions
+//
+
+func readJournal(r io.Reader) ([]*JournalEntry, error) {
+	entries := make([]*JournalEntry, 0)
+	entriesByWebhookID := make(map[string]*JournalEntry)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJournalLineSize)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, err
+		}
+
+		switch record.Type {
+		case journalRecordEvent:
+			entry := &JournalEntry{
+				ReceivedAt:            record.Time,
+				WebhookID:             record.WebhookID,
+				WebhookConversationID: record.WebhookConversationID,
+				APIVersion:            record.APIVersion,
+				EventPayload:          record.EventPayload,
+				HTTPHeaders:           record.HTTPHeaders,
+			}
+
+			var evt stripeEvent
+			if err := json.Unmarshal([]byte(record.EventPayload), &evt); err == nil {
+				entry.EventID = evt.ID
+				entry.EventType = evt.Type
+			}
+
+			entries = append(entries, entry)
+			entriesByWebhookID[record.WebhookID] = entry
+		case journalRecordResponse:
+			// Responses for events that were not journaled (e.g. because the
+			// file was truncated) are silently dropped
+			if entry, ok := entriesByWebhookID[record.WebhookID]; ok {
+				entry.Responses = append(entry.Responses, JournalResponse{
+					Time:       record.Time,
+					ForwardURL: record.ForwardURL,
+					Status:     record.Status,
+					Body:       record.Body,
+					Error:      record.Error,
+				})
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
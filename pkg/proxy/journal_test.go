@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/webhooks"
+	"github.com/stripe/stripe-cli/pkg/websocket"
+)
+
+func TestJournalRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "journal.jsonl")
+
+	journal, err := OpenJournal(path)
+	require.NoError(t, err)
+
+	apiVersion := "2020-03-02"
+	require.NoError(t, journal.RecordEvent(&websocket.WebhookEvent{
+		Endpoint:              websocket.WebhookEndpoint{APIVersion: &apiVersion},
+		EventPayload:          `{"id":"evt_123","type":"invoice.payment_failed"}`,
+		HTTPHeaders:           map[string]string{"Stripe-Signature": "t=123,v1=hunter2"},
+		WebhookConversationID: "wc_123",
+		WebhookID:             "wh_123",
+	}))
+	require.NoError(t, journal.RecordEvent(&websocket.WebhookEvent{
+		EventPayload: `{"id":"evt_456","type":"customer.created"}`,
+		WebhookID:    "wh_456",
+	}))
+	require.NoError(t, journal.RecordResponse("wh_123", "http://localhost/hooks", 500, "boom"))
+	require.NoError(t, journal.RecordError("wh_456", "http://localhost/hooks", errors.New("connection refused")))
+	require.NoError(t, journal.RecordResponse("wh_unknown", "http://localhost/hooks", 200, ""))
+	require.NoError(t, journal.Close())
+
+	entries, err := ReadJournal(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(entries))
+
+	require.Equal(t, "wh_123", entries[0].WebhookID)
+	require.Equal(t, "wc_123", entries[0].WebhookConversationID)
+	require.Equal(t, "evt_123", entries[0].EventID)
+	require.Equal(t, "invoice.payment_failed", entries[0].EventType)
+	require.Equal(t, apiVersion, *entries[0].APIVersion)
+	require.Equal(t, "t=123,v1=hunter2", entries[0].HTTPHeaders["Stripe-Signature"])
+	require.Equal(t, 1, len(entries[0].Responses))
+	require.Equal(t, 500, entries[0].Responses[0].Status)
+	require.Equal(t, "boom", entries[0].Responses[0].Body)
+
+	require.Equal(t, "evt_456", entries[1].EventID)
+	require.Nil(t, entries[1].APIVersion)
+	require.Equal(t, 1, len(entries[1].Responses))
+	require.Equal(t, "connection refused", entries[1].Responses[0].Error)
+}
+
+func TestReplay(t *testing.T) {
+	received := make([]string, 0)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		require.Equal(t, "t=123,v1=hunter2", r.Header.Get("Stripe-Signature"))
+		received = append(received, string(reqBody))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, Connect: false, EventTypes: []string{"*"}},
+		},
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	err = p.Replay([]*JournalEntry{
+		{
+			WebhookID:    "wh_123",
+			EventPayload: `{"id":"evt_123","type":"customer.created"}`,
+			HTTPHeaders:  map[string]string{"Stripe-Signature": "t=123,v1=hunter2"},
+		},
+		{
+			WebhookID:    "wh_456",
+			EventPayload: `{"id":"evt_456","type":"customer.created","account":"acct_123"}`,
+			HTTPHeaders:  map[string]string{"Stripe-Signature": "t=123,v1=hunter2"},
+		},
+	})
+
+	require.NoError(t, err)
+
+	// Connect events are not sent to non-Connect routes
+	require.Equal(t, []string{`{"id":"evt_123","type":"customer.created"}`}, received)
+}
+
+func TestReplayFailures(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"*"}},
+			{URL: "http://127.0.0.1:1", EventTypes: []string{"invoice.paid"}},
+		},
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	err = p.Replay([]*JournalEntry{
+		{WebhookID: "wh_1", EventPayload: `{"id":"evt_1","type":"customer.created"}`},
+		{WebhookID: "wh_2", EventPayload: `{"id":"evt_2","type":"customer.created"}`, HTTPHeaders: map[string]string{"X-Fail": "1"}},
+		{WebhookID: "wh_3", EventPayload: `{"id":"evt_3","type":"invoice.paid"}`},
+	})
+	require.EqualError(t, err, "2 event deliveries failed")
+
+	err = p.Replay([]*JournalEntry{
+		{WebhookID: "wh_4", EventPayload: `{"id":"evt_4","type":"customer.created"}`},
+	})
+	require.NoError(t, err)
+}
+
+func TestReplayResignsEvents(t *testing.T) {
+	payload := `{"id":"evt_123","type":"customer.created"}`
+	signatures := make([]string, 0)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatures = append(signatures, r.Header.Get(webhooks.SignatureHeader))
+	}))
+	defer ts.Close()
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"*"}},
+		},
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	p.sessionSecret.Store("whsec_123")
+
+	// The original signature was computed an hour ago
+	original := webhooks.GenerateSignatureHeader(time.Now().Add(-time.Hour), []byte(payload), "whsec_123")
+
+	err = p.Replay([]*JournalEntry{
+		{
+			WebhookID:    "wh_123",
+			EventPayload: payload,
+			HTTPHeaders:  map[string]string{webhooks.SignatureHeader: original},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, signatures, 1)
+	require.NotEqual(t, original, signatures[0])
+
+	timestamp, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signatures[0], ",")[0], "t="), 10, 64)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), time.Unix(timestamp, 0), time.Minute)
+	require.Equal(t, webhooks.GenerateSignatureHeader(time.Unix(timestamp, 0), []byte(payload), "whsec_123"), signatures[0])
+}
@@ -0,0 +1,182 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+//
+// Public types
+//
+
+// JSONLObserver writes everything that happens in a proxy session to a
+// writer as JSON lines, one typed record per line, so that the session can be
+// consumed programmatically. Every record has a `type` and a `time`; the
+// types are session_ready, reconnecting, session_expired, reconnected,
+// event_received, delivery_attempt and endpoint_response.
+type JSONLObserver struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// SessionReady writes a session_ready record.
+func (o *JSONLObserver) SessionReady(secret string) {
+	o.write(&jsonlRecord{
+		Type:   "session_ready",
+		Time:   time.Now(),
+		Secret: secret,
+	})
+}
+
+// SessionStatusChanged writes a reconnecting, session_expired or reconnected
+// record.
+func (o *JSONLObserver) SessionStatusChanged(change *SessionStatusChange) {
+	switch change.Status {
+	case SessionConnected:
+		downtime := change.Downtime.Milliseconds()
+		missed := change.MissedEvents
+
+		o.write(&jsonlRecord{
+			Type:         "reconnected",
+			Time:         change.Time,
+			DowntimeMS:   &downtime,
+			MissedEvents: &missed,
+		})
+	case SessionExpired:
+		o.write(&jsonlRecord{
+			Type:   "session_expired",
+			Time:   change.Time,
+			Reason: change.Reason,
+		})
+	default:
+		o.write(&jsonlRecord{
+			Type:   "reconnecting",
+			Time:   change.Time,
+			Reason: change.Reason,
+		})
+	}
+}
+
+// EventReceived writes an event_received record, including the event itself.
+func (o *JSONLObserver) EventReceived(evt *ReceivedEvent) {
+	o.write(&jsonlRecord{
+		Type:                  "event_received",
+		Time:                  evt.Time,
+		WebhookID:             evt.WebhookID,
+		WebhookConversationID: evt.WebhookConversationID,
+		EventID:               evt.EventID,
+		EventType:             evt.EventType,
+		Connect:               evt.Connect,
+		APIVersion:            evt.APIVersion,
+		Replayed:              evt.Replayed,
+		Backfilled:            evt.Backfilled,
+		Headers:               evt.Headers,
+		Event:                 rawJSON(evt.Payload),
+	})
+}
+
+// DeliveryAttempted writes a delivery_attempt record.
+func (o *JSONLObserver) DeliveryAttempted(attempt *DeliveryAttempt) {
+	o.write(&jsonlRecord{
+		Type:      "delivery_attempt",
+		Time:      attempt.Time,
+		WebhookID: attempt.WebhookID,
+		EventID:   attempt.EventID,
+		EventType: attempt.EventType,
+		URL:       attempt.URL,
+		Attempt:   attempt.Attempt,
+	})
+}
+
+// EndpointResponded writes an endpoint_response record.
+func (o *JSONLObserver) EndpointResponded(resp *EndpointResponse) {
+	record := &jsonlRecord{
+		Type:      "endpoint_response",
+		Time:      resp.Time,
+		WebhookID: resp.WebhookID,
+		EventID:   resp.EventID,
+		EventType: resp.EventType,
+		URL:       resp.URL,
+		Status:    &resp.Status,
+		Headers:   resp.Headers,
+		Body:      resp.Body,
+		Attempts:  resp.Attempts,
+	}
+
+	if resp.Err != nil {
+		record.Error = resp.Err.Error()
+	} else {
+		latency := resp.Latency.Milliseconds()
+		record.LatencyMS = &latency
+	}
+
+	o.write(record)
+}
+
+//
+// Public functions
+//
+
+// NewJSONLObserver returns an observer that writes JSON lines to w.
+func NewJSONLObserver(w io.Writer) *JSONLObserver {
+	return &JSONLObserver{w: w}
+}
+
+//
+// Private types
+//
+
+type jsonlRecord struct {
+	Type                  string            `json:"type"`
+	Time                  time.Time         `json:"time"`
+	Secret                string            `json:"secret,omitempty"`
+	Reason                string            `json:"reason,omitempty"`
+	DowntimeMS            *int64            `json:"downtime_ms,omitempty"`
+	MissedEvents          *int              `json:"missed_events,omitempty"`
+	WebhookID             string            `json:"webhook_id,omitempty"`
+	WebhookConversationID string            `json:"webhook_conversation_id,omitempty"`
+	EventID               string            `json:"event_id,omitempty"`
+	EventType             string            `json:"event_type,omitempty"`
+	Connect               bool              `json:"connect,omitempty"`
+	APIVersion            string            `json:"api_version,omitempty"`
+	Replayed              bool              `json:"replayed,omitempty"`
+	Backfilled            bool              `json:"backfilled,omitempty"`
+	URL                   string            `json:"url,omitempty"`
+	Attempt               int               `json:"attempt,omitempty"`
+	Attempts              int               `json:"attempts,omitempty"`
+	Status                *int              `json:"status,omitempty"`
+	LatencyMS             *int64            `json:"latency_ms,omitempty"`
+	Error                 string            `json:"error,omitempty"`
+	Headers               map[string]string `json:"headers,omitempty"`
+	Body                  string            `json:"body,omitempty"`
+	Event                 json.RawMessage   `json:"event,omitempty"`
+}
+
+//
+// Private functions
+//
+
+func (o *JSONLObserver) write(record *jsonlRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.w.Write(append(line, '\n')) //nolint:errcheck
+}
+
+// rawJSON returns payload as raw JSON if it is valid, so that it is embedded
+// as is rather than as a string.
+func rawJSON(payload string) json.RawMessage {
+	if !json.Valid([]byte(payload)) {
+		encoded, _ := json.Marshal(payload)
+		return encoded
+	}
+
+	return json.RawMessage(payload)
+}
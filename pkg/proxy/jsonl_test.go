@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readJSONLRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestJSONLObserver(t *testing.T) {
+	var buf bytes.Buffer
+
+	o := NewJSONLObserver(&buf)
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	o.SessionReady("whsec_123")
+	o.SessionStatusChanged(&SessionStatusChange{Time: now, Status: SessionExpired, Reason: "session expired"})
+	o.EventReceived(&ReceivedEvent{
+		Time:      now,
+		WebhookID: "wh_123",
+		EventID:   "evt_123",
+		EventType: "customer.created",
+		Payload:   "{\n  \"id\": \"evt_123\"\n}",
+	})
+	o.DeliveryAttempted(&DeliveryAttempt{
+		Time:      now,
+		WebhookID: "wh_123",
+		EventID:   "evt_123",
+		URL:       "http://localhost:4000",
+		Attempt:   1,
+	})
+	o.EndpointResponded(&EndpointResponse{
+		Time:      now,
+		WebhookID: "wh_123",
+		EventID:   "evt_123",
+		URL:       "http://localhost:4000",
+		Status:    400,
+		Body:      "Bad request",
+		Latency:   42 * time.Millisecond,
+		Attempts:  1,
+	})
+	o.EndpointResponded(&EndpointResponse{
+		Time:     now,
+		URL:      "http://localhost:5000",
+		Err:      errors.New("connection refused"),
+		Attempts: 3,
+	})
+
+	records := readJSONLRecords(t, &buf)
+	require.Len(t, records, 6)
+
+	require.Equal(t, "session_ready", records[0]["type"])
+	require.Equal(t, "whsec_123", records[0]["secret"])
+
+	require.Equal(t, "session_expired", records[1]["type"])
+	require.Equal(t, "session expired", records[1]["reason"])
+
+	require.Equal(t, "event_received", records[2]["type"])
+	require.Equal(t, "2020-05-01T12:00:00Z", records[2]["time"])
+	require.Equal(t, "evt_123", records[2]["event_id"])
+	require.Equal(t, map[string]interface{}{"id": "evt_123"}, records[2]["event"])
+
+	require.Equal(t, "delivery_attempt", records[3]["type"])
+	require.Equal(t, float64(1), records[3]["attempt"])
+
+	require.Equal(t, "endpoint_response", records[4]["type"])
+	require.Equal(t, float64(400), records[4]["status"])
+	require.Equal(t, float64(42), records[4]["latency_ms"])
+	require.Equal(t, "Bad request", records[4]["body"])
+
+	require.Equal(t, float64(0), records[5]["status"])
+	require.Equal(t, "connection refused", records[5]["error"])
+	require.NotContains(t, records[5], "latency_ms")
+}
+
+func TestJSONLObserverSessionStatus(t *testing.T) {
+	var buf bytes.Buffer
+
+	o := NewJSONLObserver(&buf)
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	o.SessionStatusChanged(&SessionStatusChange{Time: now, Status: SessionReconnecting, Reason: "connection lost: EOF"})
+	o.SessionStatusChanged(&SessionStatusChange{Time: now, Status: SessionConnected, Downtime: 90 * time.Second})
+	o.SessionStatusChanged(&SessionStatusChange{Time: now, Status: SessionConnected, MissedEvents: -1})
+
+	records := readJSONLRecords(t, &buf)
+	require.Len(t, records, 3)
+
+	require.Equal(t, "reconnecting", records[0]["type"])
+	require.Equal(t, "connection lost: EOF", records[0]["reason"])
+
+	require.Equal(t, "reconnected", records[1]["type"])
+	require.Equal(t, float64(90000), records[1]["downtime_ms"])
+	require.Equal(t, float64(0), records[1]["missed_events"])
+
+	require.Equal(t, float64(-1), records[2]["missed_events"])
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"time"
+)
+
+//
+// Public types
+//
+
+// Observer is notified of everything that happens in a proxy session. It
+// allows alternative frontends (such as the interactive listen UI) to be built
+// on top of the proxy. Methods may be called concurrently.
+type Observer interface {
+	// SessionReady is called when the websocket connection to Stripe is
+	// established with a new session, with the session's webhook signing
+	// secret.
+	SessionReady(secret string)
+
+	// SessionStatusChanged is called when the connection with Stripe is lost,
+	// when the session expires, and when the proxy is connected again.
+	SessionStatusChanged(change *SessionStatusChange)
+
+	// EventReceived is called for every event that passes the proxy's
+	// filters, before it is forwarded to the local endpoints.
+	EventReceived(evt *ReceivedEvent)
+
+	// DeliveryAttempted is called before every attempt to forward an event
+	// to a local endpoint, including retries.
+	DeliveryAttempted(attempt *DeliveryAttempt)
+
+	// EndpointResponded is called with the final outcome of forwarding an
+	// event to a local endpoint.
+	EndpointResponded(resp *EndpointResponse)
+}
+
+// SessionStatus is the state of the connection with Stripe.
+type SessionStatus string
+
+// The states of the connection with Stripe.
+const (
+	// SessionConnected means that events are received again after a
+	// disconnection
+	SessionConnected SessionStatus = "connected"
+
+	// SessionReconnecting means that the connection was lost, or that a new
+	// session could not be created, and that the proxy is trying again
+	SessionReconnecting SessionStatus = "reconnecting"
+
+	// SessionExpired means that the session expired and that a new one must
+	// be created
+	SessionExpired SessionStatus = "expired"
+)
+
+// SessionStatusChange describes a change in the state of the connection with
+// Stripe.
+type SessionStatusChange struct {
+	Time   time.Time
+	Status SessionStatus
+
+	// Reason is the cause of the disconnection, for the reconnecting and
+	// expired statuses
+	Reason string
+
+	// Downtime is how long the proxy was disconnected, for the connected
+	// status
+	Downtime time.Duration
+
+	// MissedEvents is the number of events matching the listened types that
+	// were created while the proxy was disconnected and that may not have
+	// been received, for the connected status. They are only counted when
+	// backfilling, and it is -1 otherwise or if they could not be counted.
+	// Counting stops at 1000.
+	MissedEvents int
+}
+
+// ReceivedEvent describes a webhook event received by the proxy.
+type ReceivedEvent struct {
+	Time                  time.Time
+	WebhookID             string
+	WebhookConversationID string
+	EventID               string
+	EventType             string
+	Connect               bool
+	Payload               string
+	Headers               map[string]string
+
+	// APIVersion is the API version the event was rendered with
+	APIVersion string
+
+	// Replayed is true for events that were re-sent locally rather than
+	// delivered by Stripe
+	Replayed bool
+
+	// Backfilled is true for events that were created while the proxy was
+	// disconnected from Stripe, and fetched from the API once reconnected
+	Backfilled bool
+}
+
+// DeliveryAttempt describes an attempt to forward an event to a local
+// endpoint.
+type DeliveryAttempt struct {
+	Time      time.Time
+	WebhookID string
+	EventID   string
+	EventType string
+	URL       string
+
+	// Attempt is the number of the attempt, starting at 1
+	Attempt int
+}
+
+// EndpointResponse describes the outcome of forwarding an event to a local
+// endpoint.
+type EndpointResponse struct {
+	Time      time.Time
+	WebhookID string
+	EventID   string
+	EventType string
+	URL       string
+
+	// Status is the HTTP status returned by the endpoint, or 0 if the
+	// endpoint could not be reached (in which case Err is set)
+	Status  int
+	Body    string
+	Headers map[string]string
+	Err     error
+
+	// Latency is the duration of the last delivery attempt
+	Latency time.Duration
+
+	Attempts int
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	mu        sync.Mutex
+	events    []*ReceivedEvent
+	attempts  []*DeliveryAttempt
+	responses []*EndpointResponse
+	statuses  []*SessionStatusChange
+}
+
+func (o *recordingObserver) SessionReady(secret string) {}
+
+func (o *recordingObserver) SessionStatusChanged(change *SessionStatusChange) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.statuses = append(o.statuses, change)
+}
+
+func (o *recordingObserver) DeliveryAttempted(attempt *DeliveryAttempt) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.attempts = append(o.attempts, attempt)
+}
+
+func (o *recordingObserver) EventReceived(evt *ReceivedEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, evt)
+}
+
+func (o *recordingObserver) EndpointResponded(resp *EndpointResponse) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.responses = append(o.responses, resp)
+}
+
+func TestObserver(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	observer := &recordingObserver{}
+	var out bytes.Buffer
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"*"}},
+			{URL: "http://localhost:1", EventTypes: []string{"*"}},
+		},
+		Out:      &out,
+		Observer: observer,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	p.Replay([]*JournalEntry{
+		{
+			WebhookID:    "wh_123",
+			EventPayload: `{"id":"evt_123","type":"customer.created"}`,
+		},
+	})
+
+	require.Len(t, observer.events, 1)
+	require.Equal(t, "evt_123", observer.events[0].EventID)
+	require.Equal(t, "customer.created", observer.events[0].EventType)
+	require.True(t, observer.events[0].Replayed)
+
+	require.Len(t, observer.attempts, 2)
+	require.Equal(t, 1, observer.attempts[0].Attempt)
+
+	require.Len(t, observer.responses, 2)
+
+	resp := observer.responses[0]
+	require.Equal(t, "wh_123", resp.WebhookID)
+	require.Equal(t, ts.URL, resp.URL)
+	require.Equal(t, http.StatusAccepted, resp.Status)
+	require.Equal(t, `{"ok":true}`, resp.Body)
+	require.Equal(t, "application/json", resp.Headers["Content-Type"])
+	require.Equal(t, 1, resp.Attempts)
+	require.True(t, resp.Latency > 0)
+
+	require.Equal(t, "http://localhost:1", observer.responses[1].URL)
+	require.Error(t, observer.responses[1].Err)
+
+	require.Contains(t, out.String(), "evt_123")
+}
@@ -2,20 +2,24 @@ package proxy
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/briandowns/spinner"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/stripe/stripe-cli/pkg/ansi"
 	"github.com/stripe/stripe-cli/pkg/stripeauth"
+	"github.com/stripe/stripe-cli/pkg/webhooks"
 	"github.com/stripe/stripe-cli/pkg/websocket"
 )
 
@@ -27,7 +31,8 @@ const timeLayout = "2006-01-02 15:04:05"
 
 // EndpointRoute describes a local endpoint's routing configuration.
 type EndpointRoute struct {
-	// URL is the endpoint's URL.
+	// URL is the endpoint's URL. Besides HTTP(S) URLs, it can designate an
+	// `exec:`, `file:` or `unix:` sink.
 	URL string
 
 	// Headers to forward to endpoints
@@ -37,7 +42,30 @@ type EndpointRoute struct {
 	Connect bool
 
 	// EventTypes is the list of event types that should be sent to the endpoint.
+	// Entries may be globs such as `invoice.*` or negations such as `!charge.updated`.
 	EventTypes []string
+
+	// Retry is the policy used when forwarding an event to the endpoint fails.
+	Retry RetryPolicy
+
+	// SkipVerify indicates whether to skip certificate verification when forwarding to this endpoint.
+	SkipVerify bool
+
+	// TLS configures the CA bundle, client certificate and server name used
+	// when forwarding to this endpoint over HTTPS.
+	TLS TLSConfig
+
+	// SigningSecret, if set, is used to re-sign events forwarded to this endpoint
+	// instead of passing along the signature computed by Stripe.
+	SigningSecret string
+
+	// APIVersion, if set, pins the endpoint to an API version: it receives the
+	// events rendered with that version, whether it is the account's default or
+	// the latest one, regardless of UseLatestAPIVersion.
+	APIVersion string
+
+	// Timeout is the maximum time to wait for the endpoint to respond (default: 30s).
+	Timeout time.Duration
 }
 
 // Config provides the configuration of a Proxy
@@ -59,6 +87,10 @@ type Config struct {
 	// Indicates whether to print full JSON objects to stdout
 	PrintJSON bool
 
+	// ShowResponseDetails indicates whether to print the latency of every
+	// delivery and the body of non-2xx responses
+	ShowResponseDetails bool
+
 	// Indicates whether to filter events formatted with the default or latest API version
 	UseLatestAPIVersion bool
 
@@ -69,6 +101,53 @@ type Config struct {
 
 	// Force use of unencrypted ws:// protocol instead of wss://
 	NoWSS bool
+
+	// Journal, if set, records every received event and endpoint response
+	Journal *Journal
+
+	// MaxConcurrentDeliveries limits the number of deliveries to local endpoints
+	// in flight at any given time. 0 means DefaultMaxConcurrentDeliveries.
+	MaxConcurrentDeliveries int
+
+	// OrderedDelivery indicates whether events about the same object should be
+	// delivered to an endpoint sequentially, in the order they were received
+	OrderedDelivery bool
+
+	// Out is where received events and endpoint responses are printed (default: os.Stdout)
+	Out io.Writer
+
+	// Observer, if set, is notified of sessions, events and endpoint responses
+	Observer Observer
+
+	// LocalAddr, if set, is the address of a local HTTP port on which events
+	// can be POSTed, in addition to the events delivered by Stripe
+	LocalAddr string
+
+	// LocalOnly indicates whether to only accept events on LocalAddr, without
+	// connecting to Stripe
+	LocalOnly bool
+
+	// Filters are conditions on the payload that events must all satisfy to
+	// be printed and forwarded
+	Filters []*PayloadFilter
+
+	// Transforms are applied, in order, to the events before they are
+	// forwarded to the endpoints
+	Transforms []Transform
+
+	// ShutdownTimeout is how long to wait for the deliveries in progress to
+	// complete when the proxy is stopped (default: 10s)
+	ShutdownTimeout time.Duration
+
+	// Backfill indicates whether to forward the events created while the
+	// proxy was disconnected from Stripe, fetched from the API once it is
+	// connected again
+	Backfill bool
+
+	// UnlimitedSessionRenewal indicates whether to keep creating new sessions
+	// when they expire or cannot be created, with a growing delay between
+	// attempts, instead of giving up after 3 consecutive failures
+	UnlimitedSessionRenewal bool
 }
 
 // A Proxy opens a websocket connection with Stripe, listens for incoming
@@ -78,51 +157,149 @@ type Proxy struct {
 	cfg *Config
 
 	endpointClients  []*EndpointClient
+	dispatcher       *dispatcher
 	stripeAuthClient *stripeauth.Client
 	webSocketClient  *websocket.Client
-
-	// Events is the supported event types for the command
-	events map[string]bool
-}
-
-func withSIGTERMCancel(ctx context.Context, onCancel func()) context.Context {
-	// Create a context that will be canceled when Ctrl+C is pressed
-	ctx, cancel := context.WithCancel(ctx)
-
-	interruptCh := make(chan os.Signal, 1)
-	signal.Notify(interruptCh, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		<-interruptCh
-		onCancel()
-		cancel()
-	}()
-	return ctx
+	summary          *summary
+
+	// ingressCount is the number of events received on the local ingress
+	ingressCount int32
+
+	// stopping is set to 1 once the proxy stops accepting new events
+	stopping int32
+
+	// deliveryCtx is canceled when the proxy gives up waiting for the
+	// deliveries in progress, to interrupt their requests and retries
+	deliveryCtx    context.Context
+	cancelDelivery context.CancelFunc
+
+	// sendMu guards the websocket client, which is replaced when the session
+	// is renewed, and sends to it, which must not happen once it has been
+	// stopped
+	sendMu           sync.Mutex
+	webSocketStopped bool
+
+	// ready is closed once the proxy is ready to receive events
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	// localAddr is the address the local ingress listens on, if enabled
+	localAddr net.Addr
+
+	// sessionSecret is the webhook signing secret of the current session
+	sessionSecret atomic.Value
+
+	// latestAPIVersion and defaultAPIVersion are the latest and the
+	// account's default API versions, learned from the events Stripe renders
+	// with them
+	latestAPIVersion  atomic.Value
+	defaultAPIVersion atomic.Value
+
+	// pinnedVersionsChecked ensures that unreachable pinned API versions are
+	// only reported once
+	pinnedVersionsChecked sync.Once
+
+	// spinner displays the state of the connection with Stripe
+	spinner *spinner.Spinner
+
+	// disconnectedAt is when the connection with Stripe was lost, or zero
+	// while connected. lastCreated is the creation time of the most recent
+	// event received from Stripe, and seen the IDs of the events received
+	// around that time, so that they are not backfilled.
+	connMu         sync.Mutex
+	disconnectedAt time.Time
+	lastCreated    int64
+	seen           map[string]int64
+
+	// backfillCount is the number of events backfilled so far
+	backfillCount int32
+
+	// pinnedDeliveries are the events recently forwarded to endpoints pinned
+	// to an API version
+	pinnedDeliveries *deliveredSet
+
+	// Events is the supported event type patterns for the command
+	events []string
 }
 
 const maxConnectAttempts = 3
 
 // Run sets the websocket connection and starts the Goroutines to forward
-// incoming events to the local endpoint.
+// incoming events to the local endpoint. It blocks until ctx is done, then
+// waits for the deliveries in progress and returns. Signal handling is left to
+// the caller.
 func (p *Proxy) Run(ctx context.Context) error {
-	s := ansi.StartNewSpinner("Getting ready...", p.cfg.Log.Out)
+	p.spinner = ansi.StartNewSpinner("Getting ready...", p.cfg.Log.Out)
 
-	ctx = withSIGTERMCancel(ctx, func() {
-		log.WithFields(log.Fields{
-			"prefix": "proxy.Proxy.Run",
-		}).Debug("Ctrl+C received, cleaning up...")
-	})
+	// The websocket connection is kept open while draining the deliveries in
+	// progress on shutdown, so that their responses can still be sent to
+	// Stripe
+	wsCtx, stopWebSocket := context.WithCancel(context.Background())
+	defer stopWebSocket()
+
+	maybeIngress := ""
+
+	if p.cfg.LocalAddr != "" {
+		addr, err := p.listenLocalIngress(ctx)
+		if err != nil {
+			ansi.StopSpinner(p.spinner, "", p.cfg.Log.Out)
+			return fmt.Errorf("Error while starting the local ingress: %v", err)
+		}
 
-	var nAttempts int = 0
+		p.localAddr = addr
+
+		if p.cfg.LocalOnly {
+			ansi.StopSpinner(p.spinner, fmt.Sprintf("Ready! Accepting events on http://%s (^C to quit)", addr), p.cfg.Log.Out)
+			p.setReady("")
+
+			<-ctx.Done()
+
+			return p.shutdown(stopWebSocket)
+		}
+
+		maybeIngress = fmt.Sprintf(", also accepting events on http://%s", addr)
+	}
+
+	// failures is the number of consecutive attempts to create a session
+	// that did not result in a connection
+	var failures int32
+
+	for {
+		if n := int(atomic.LoadInt32(&failures)); n > 0 {
+			select {
+			case <-ctx.Done():
+				ansi.StopSpinner(p.spinner, "", p.cfg.Log.Out)
+				return p.shutdown(stopWebSocket)
+			case <-time.After(renewalBackoff(n)):
+			}
+		}
 
-	for nAttempts < maxConnectAttempts {
 		session, err := p.createSession(ctx)
 		if err != nil {
-			ansi.StopSpinner(s, "", p.cfg.Log.Out)
-			p.cfg.Log.Fatalf("Error while authenticating with Stripe: %v", err)
+			if ctx.Err() != nil {
+				ansi.StopSpinner(p.spinner, "", p.cfg.Log.Out)
+				return p.shutdown(stopWebSocket)
+			}
+
+			// Once connected, keep trying to create a new session if asked
+			// to, e.g. while the network is down
+			if p.cfg.UnlimitedSessionRenewal && p.isReady() {
+				n := atomic.AddInt32(&failures, 1)
+				p.disconnected(SessionReconnecting, fmt.Sprintf("cannot create a session: %v", err),
+					fmt.Sprintf("Could not create a new session, retrying in %s...", renewalBackoff(int(n))))
+
+				continue
+			}
+
+			ansi.StopSpinner(p.spinner, "", p.cfg.Log.Out)
+
+			return fmt.Errorf("Error while authenticating with Stripe: %v", err)
 		}
 
-		p.webSocketClient = websocket.NewClient(
+		previousSecret, _ := p.sessionSecret.Load().(string)
+		p.sessionSecret.Store(session.Secret)
+
+		client := websocket.NewClient(
 			session.WebSocketURL,
 			session.WebSocketID,
 			session.WebSocketAuthorizedFeature,
@@ -131,51 +308,143 @@ func (p *Proxy) Run(ctx context.Context) error {
 				NoWSS:             p.cfg.NoWSS,
 				ReconnectInterval: time.Duration(session.ReconnectDelay) * time.Second,
 				EventHandler:      websocket.EventHandlerFunc(p.processWebhookEvent),
+				ConnectionHandler: websocket.ConnectionHandlerFunc(p.processConnectionChange),
 			},
 		)
 
+		p.sendMu.Lock()
+		p.webSocketClient = client
+		p.sendMu.Unlock()
+
 		go func() {
-			<-p.webSocketClient.Connected()
-			nAttempts = 0
-			ansi.StopSpinner(s, fmt.Sprintf("Ready! Your webhook signing secret is %s (^C to quit)", ansi.Bold(session.Secret)), p.cfg.Log.Out)
+			<-client.Connected()
+			atomic.StoreInt32(&failures, 0)
+
+			if !p.isReady() {
+				ansi.StopSpinner(p.spinner, fmt.Sprintf("Ready! Your webhook signing secret is %s%s (^C to quit)", ansi.Bold(session.Secret), maybeIngress), p.cfg.Log.Out)
+				p.setReady(session.Secret)
+
+				return
+			}
+
+			p.setReady(session.Secret)
+			p.reconnected()
+
+			if session.Secret != previousSecret {
+				fmt.Fprintf(p.cfg.Log.Out, "Your webhook signing secret is now %s\n", ansi.Bold(session.Secret))
+			}
 		}()
 
-		go p.webSocketClient.Run(ctx)
-		nAttempts++
+		go client.Run(wsCtx)
 
 		select {
 		case <-ctx.Done():
-			ansi.StopSpinner(s, "", p.cfg.Log.Out)
-			p.cfg.Log.Fatalf("Aborting")
-		case <-p.webSocketClient.NotifyExpired:
-			if nAttempts < maxConnectAttempts {
-				ansi.StartSpinner(s, "Session expired, reconnecting...", p.cfg.Log.Out)
-			} else {
-				p.cfg.Log.Fatalf("Session expired. Terminating after %d failed attempts to reauthorize", nAttempts)
+			ansi.StopSpinner(p.spinner, "", p.cfg.Log.Out)
+
+			return p.shutdown(stopWebSocket)
+		case <-client.NotifyExpired:
+			n := atomic.AddInt32(&failures, 1)
+
+			if !p.cfg.UnlimitedSessionRenewal && n >= maxConnectAttempts {
+				p.shutdown(stopWebSocket) //nolint:errcheck
+				return fmt.Errorf("Session expired. Terminating after %d failed attempts to reauthorize", n)
+			}
+
+			msg := "Session expired, reconnecting..."
+			if backoff := renewalBackoff(int(n)); backoff > 0 {
+				msg = fmt.Sprintf("Session expired, reconnecting in %s...", backoff)
 			}
+
+			p.disconnected(SessionExpired, "session expired", msg)
 		}
 	}
+}
+
+// shutdown stops accepting new events and waits, for up to the shutdown
+// timeout, for the deliveries in progress to complete so that their responses
+// are still reported to Stripe, then interrupts the remaining ones. It returns
+// an error if any delivery failed or did not complete in time.
+func (p *Proxy) shutdown(stopWebSocket context.CancelFunc) error {
+	atomic.StoreInt32(&p.stopping, 1)
+
+	if pending := p.dispatcher.close(); pending > 0 {
+		fmt.Fprintf(p.cfg.Log.Out, "Waiting for %d event deliveries in progress to complete...\n", pending)
+	}
+
+	pending := p.dispatcher.wait(p.cfg.ShutdownTimeout)
+	p.cancelDelivery()
+
+	p.sendMu.Lock()
+	p.webSocketStopped = true
+	p.sendMu.Unlock()
+
+	stopWebSocket()
 
-	if p.webSocketClient != nil {
-		p.webSocketClient.Stop()
+	if !p.cfg.PrintJSON {
+		p.summary.print(p.cfg.Out)
 	}
 
 	log.WithFields(log.Fields{
-		"prefix": "proxy.Proxy.Run",
+		"prefix": "proxy.Proxy.shutdown",
 	}).Debug("Bye!")
 
+	if pending > 0 {
+		return fmt.Errorf("Gave up waiting for %d event deliveries in progress", pending)
+	}
+
+	if failed := p.summary.failures(); failed > 0 {
+		return fmt.Errorf("%d event deliveries failed", failed)
+	}
+
 	return nil
 }
 
-// GetSessionSecret creates a session and returns the webhook signing secret.
+// GetSessionSecret creates a session and returns the webhook signing secret,
+// which is then also used to sign replayed events.
 func (p *Proxy) GetSessionSecret(ctx context.Context) (string, error) {
 	session, err := p.createSession(ctx)
 	if err != nil {
-		p.cfg.Log.Fatalf("Error while authenticating with Stripe: %v", err)
+		return "", fmt.Errorf("Error while authenticating with Stripe: %v", err)
 	}
+
+	p.sessionSecret.Store(session.Secret)
+
 	return session.Secret, nil
 }
 
+// Ready returns a channel that is closed once the proxy is ready to receive
+// events, i.e. once connected to Stripe or, in local only mode, once the
+// local ingress accepts events.
+func (p *Proxy) Ready() <-chan struct{} {
+	return p.ready
+}
+
+// LocalAddr returns the address of the local ingress once the proxy is
+// ready, or nil if the local ingress is disabled.
+func (p *Proxy) LocalAddr() net.Addr {
+	return p.localAddr
+}
+
+// isReady reports whether the proxy has been ready to receive events.
+func (p *Proxy) isReady() bool {
+	select {
+	case <-p.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Proxy) setReady(secret string) {
+	if p.cfg.Observer != nil {
+		p.cfg.Observer.SessionReady(secret)
+	}
+
+	p.readyOnce.Do(func() {
+		close(p.ready)
+	})
+}
+
 func (p *Proxy) createSession(ctx context.Context) (*stripeauth.StripeCLISession, error) {
 	var session *stripeauth.StripeCLISession
 
@@ -244,8 +513,20 @@ func (p *Proxy) processWebhookEvent(msg websocket.IncomingMessage) {
 		"webhook_converesation_id": webhookEvent.WebhookConversationID,
 	}).Debugf("Processing webhook event")
 
-	if p.filterWebhookEvent(webhookEvent) {
-		return
+	p.handleWebhookEvent(webhookEvent, sourceStripe)
+}
+
+// handleWebhookEvent prints, journals and forwards an event to the endpoints
+// that support it. The responses to events that were not delivered by Stripe
+// are not reported back. It returns what became of the event.
+func (p *Proxy) handleWebhookEvent(webhookEvent *websocket.WebhookEvent, source eventSource) eventOutcome {
+	if atomic.LoadInt32(&p.stopping) == 1 {
+		p.cfg.Log.WithFields(log.Fields{
+			"prefix":     "proxy.Proxy.handleWebhookEvent",
+			"webhook_id": webhookEvent.WebhookID,
+		}).Debugf("Shutting down, ignoring event")
+
+		return eventDropped
 	}
 
 	var evt stripeEvent
@@ -253,61 +534,334 @@ func (p *Proxy) processWebhookEvent(msg websocket.IncomingMessage) {
 	err := json.Unmarshal([]byte(webhookEvent.EventPayload), &evt)
 	if err != nil {
 		p.cfg.Log.Debug("Received malformed event from Stripe, ignoring")
-		return
+		return eventDropped
+	}
+
+	// Stripe delivers every event rendered with both the account's default
+	// and the latest API versions. The one selected by the proxy's setting is
+	// the primary one; the other is only forwarded to the endpoints pinned to
+	// its version.
+	version := eventAPIVersion(webhookEvent.Endpoint.APIVersion, &evt)
+
+	var primary bool
+
+	switch source {
+	case sourceStripe:
+		primary = !p.filterWebhookEvent(webhookEvent)
+
+		if webhookEvent.Endpoint.APIVersion != nil {
+			p.latestAPIVersion.Store(version)
+		} else {
+			p.defaultAPIVersion.Store(version)
+		}
+
+		p.checkPinnedAPIVersions()
+	case sourceBackfillPinned:
+		primary = false
+	default:
+		primary = true
+	}
+
+	if !primary && !p.hasPinnedEndpoint(version) {
+		return eventFiltered
+	}
+
+	if p.cfg.Journal != nil {
+		if err := p.cfg.Journal.RecordEvent(webhookEvent); err != nil {
+			p.cfg.Log.Debugf("Failed to write event to journal: %v", err)
+		}
 	}
 
 	evtCtx := eventContext{
 		webhookID:             webhookEvent.WebhookID,
 		webhookConversationID: webhookEvent.WebhookConversationID,
 		event:                 &evt,
+		local:                 source != sourceStripe,
 	}
 
-	if p.events["*"] || p.events[evt.Type] {
-		if p.cfg.PrintJSON {
-			fmt.Println(webhookEvent.EventPayload)
-		} else {
-			maybeConnect := ""
-			if evt.isConnect() {
-				maybeConnect = "connect "
-			}
+	if source != sourceIngress {
+		p.recordSeen(&evt)
+	}
+
+	if !EventTypeMatches(p.events, evt.Type) || !p.matchesFilters(webhookEvent.EventPayload) {
+		return eventFiltered
+	}
+
+	endpoints := p.selectEndpoints(&evt, version, primary, source != sourceIngress)
+	if !primary && len(endpoints) == 0 {
+		return eventFiltered
+	}
+
+	note := ""
+
+	switch {
+	case source.backfilled():
+		note = "backfilled"
+	case !primary:
+		note = "API version " + version
+	}
+
+	p.printEvent(&evt, webhookEvent.EventPayload, note)
+	p.summary.recordEvent(evt.Type)
+
+	if p.cfg.Observer != nil {
+		p.cfg.Observer.EventReceived(&ReceivedEvent{
+			Time:                  time.Now(),
+			WebhookID:             webhookEvent.WebhookID,
+			WebhookConversationID: webhookEvent.WebhookConversationID,
+			EventID:               evt.ID,
+			EventType:             evt.Type,
+			APIVersion:            version,
+			Connect:               evt.isConnect(),
+			Payload:               webhookEvent.EventPayload,
+			Headers:               webhookEvent.HTTPHeaders,
+			Backfilled:            source.backfilled(),
+		})
+	}
+
+	payload, headers, ok := p.transformEvent(&evt, webhookEvent.EventPayload, webhookEvent.HTTPHeaders)
+	if !ok {
+		return eventDropped
+	}
+
+	for _, endpoint := range endpoints {
+		endpoint := endpoint
+
+		p.dispatcher.dispatch(endpoint.URL+" "+evt.objectID(), func() {
+			p.postToEndpoint(endpoint, evtCtx, payload, headers)
+		})
+	}
 
-			localTime := time.Now().Format(timeLayout)
+	return eventForwarded
+}
+
+// Replay forwards journaled events to the configured endpoint routes, without
+// going through Stripe. Events are delivered sequentially, in order, and the
+// endpoints' responses are not reported back to Stripe. Events are signed
+// again with the session's secret, if known, since their original signature
+// has likely expired. It returns an error if any delivery failed.
+func (p *Proxy) Replay(entries []*JournalEntry) error {
+	var failures int32
+
+	for _, entry := range entries {
+		var evt stripeEvent
+
+		err := json.Unmarshal([]byte(entry.EventPayload), &evt)
+		if err != nil {
+			p.cfg.Log.Debugf("Skipping malformed journal entry %s", entry.WebhookID)
+			continue
+		}
 
-			color := ansi.Color(os.Stdout)
-			outputStr := fmt.Sprintf("%s   --> %s%s [%s]",
-				color.Faint(localTime),
-				maybeConnect,
-				ansi.Linkify(ansi.Bold(evt.Type), evt.urlForEventType(), p.cfg.Log.Out),
-				ansi.Linkify(evt.ID, evt.urlForEventID(), p.cfg.Log.Out),
-			)
-			fmt.Println(outputStr)
+		evtCtx := eventContext{
+			webhookID:             entry.WebhookID,
+			webhookConversationID: entry.WebhookConversationID,
+			event:                 &evt,
+			local:                 true,
+			failures:              &failures,
 		}
 
-		for _, endpoint := range p.endpointClients {
-			if endpoint.SupportsEventType(evt.isConnect(), evt.Type) {
-				// TODO: handle errors returned by endpointClients
-				go endpoint.Post(
-					evtCtx,
-					webhookEvent.EventPayload,
-					webhookEvent.HTTPHeaders,
-				)
-			}
+		p.printEvent(&evt, entry.EventPayload, "")
+
+		if p.cfg.Observer != nil {
+			p.cfg.Observer.EventReceived(&ReceivedEvent{
+				Time:                  time.Now(),
+				WebhookID:             entry.WebhookID,
+				WebhookConversationID: entry.WebhookConversationID,
+				EventID:               evt.ID,
+				EventType:             evt.Type,
+				APIVersion:            eventAPIVersion(entry.APIVersion, &evt),
+				Connect:               evt.isConnect(),
+				Payload:               entry.EventPayload,
+				Headers:               entry.HTTPHeaders,
+				Replayed:              true,
+			})
+		}
+
+		payload, headers, ok := p.transformEvent(&evt, entry.EventPayload, p.resignedHeaders(entry.EventPayload, entry.HTTPHeaders))
+		if !ok {
+			atomic.AddInt32(&failures, 1)
+			continue
+		}
+
+		// Renderings that were only forwarded to the endpoints pinned to
+		// their version are not replayed to the other endpoints
+		version := eventAPIVersion(entry.APIVersion, &evt)
+		primary := !p.hasPinnedEndpoint(version) || p.isPrimaryAPIVersion(version)
+
+		for _, endpoint := range p.selectEndpoints(&evt, version, primary, false) {
+			p.postToEndpoint(endpoint, evtCtx, payload, headers)
 		}
 	}
+
+	if n := atomic.LoadInt32(&failures); n > 0 {
+		return fmt.Errorf("%d event deliveries failed", n)
+	}
+
+	return nil
+}
+
+// resignedHeaders returns a copy of headers with a fresh signature of
+// payload, computed with the session's signing secret. The headers are
+// returned unchanged if there is no session secret.
+func (p *Proxy) resignedHeaders(payload string, headers map[string]string) map[string]string {
+	secret, _ := p.sessionSecret.Load().(string)
+	if secret == "" {
+		return headers
+	}
+
+	resigned := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		resigned[k] = v
+	}
+
+	resigned[webhooks.SignatureHeader] = webhooks.GenerateSignatureHeader(time.Now(), []byte(payload), secret)
+
+	return resigned
+}
+
+// responseBodyIndent aligns response bodies with the status of the response
+// line they belong to.
+var responseBodyIndent = strings.Repeat(" ", len(timeLayout)+len("  <--  "))
+
+// printResponseBody prints the body of an endpoint's response below the
+// response line, indented and truncated.
+func (p *Proxy) printResponseBody(body string) {
+	body = truncate(strings.TrimSpace(body), maxDisplayedBodySize, true)
+
+	color := ansi.Color(p.cfg.Out)
+
+	for _, line := range strings.Split(body, "\n") {
+		fmt.Fprintf(p.cfg.Out, "%s%s\n", responseBodyIndent, color.Faint(line))
+	}
+}
+
+// printEvent prints an event, followed by note in parentheses if not empty.
+func (p *Proxy) printEvent(evt *stripeEvent, payload string, note string) {
+	if p.cfg.PrintJSON {
+		fmt.Fprintln(p.cfg.Out, payload)
+		return
+	}
+
+	maybeConnect := ""
+	if evt.isConnect() {
+		maybeConnect = "connect "
+	}
+
+	localTime := time.Now().Format(timeLayout)
+
+	color := ansi.Color(p.cfg.Out)
+	outputStr := fmt.Sprintf("%s   --> %s%s [%s]",
+		color.Faint(localTime),
+		maybeConnect,
+		ansi.Linkify(color.Bold(evt.Type).String(), evt.urlForEventType(), p.cfg.Out),
+		ansi.Linkify(evt.ID, evt.urlForEventID(), p.cfg.Out),
+	)
+
+	if note != "" {
+		outputStr += " " + color.Faint("("+note+")").String()
+	}
+
+	fmt.Fprintln(p.cfg.Out, outputStr)
+}
+
+func (p *Proxy) postToEndpoint(endpoint *EndpointClient, evtCtx eventContext, payload string, headers map[string]string) {
+	err := endpoint.Post(p.deliveryCtx, evtCtx, payload, headers)
+	if err == nil {
+		return
+	}
+
+	if p.cfg.Journal != nil {
+		if err := p.cfg.Journal.RecordError(evtCtx.webhookID, endpoint.URL, err); err != nil {
+			p.cfg.Log.Debugf("Failed to write endpoint error to journal: %v", err)
+		}
+	}
+
+	attempts := 1
+	if deliveryErr, ok := err.(*DeliveryError); ok {
+		attempts = deliveryErr.Attempts
+	}
+
+	p.summary.recordResponse(evtCtx.event.Type, 0)
+
+	if evtCtx.failures != nil {
+		atomic.AddInt32(evtCtx.failures, 1)
+	}
+
+	if p.cfg.Observer != nil {
+		p.cfg.Observer.EndpointResponded(&EndpointResponse{
+			Time:      time.Now(),
+			WebhookID: evtCtx.webhookID,
+			EventID:   evtCtx.event.ID,
+			EventType: evtCtx.event.Type,
+			URL:       endpoint.URL,
+			Err:       err,
+			Attempts:  attempts,
+		})
+	}
+
+	// Let Stripe know that the delivery was given up on. A status of 0
+	// indicates that no response was received from the endpoint.
+	if !evtCtx.local {
+		body := err.Error()
+		if attempts > 1 {
+			body = fmt.Sprintf("Gave up after %d attempts: %v", attempts, err)
+		}
+
+		msg := websocket.NewWebhookResponse(
+			evtCtx.webhookID,
+			evtCtx.webhookConversationID,
+			endpoint.URL,
+			0,
+			truncate(body, maxBodySize, true),
+			map[string]string{},
+		)
+		p.sendMessage(msg)
+	}
+}
+
+func (p *Proxy) processEndpointAttempt(evtCtx eventContext, forwardURL string) {
+	if p.cfg.Observer != nil {
+		p.cfg.Observer.DeliveryAttempted(&DeliveryAttempt{
+			Time:      time.Now(),
+			WebhookID: evtCtx.webhookID,
+			EventID:   evtCtx.event.ID,
+			EventType: evtCtx.event.Type,
+			URL:       forwardURL,
+			Attempt:   evtCtx.attempts,
+		})
+	}
 }
 
 func (p *Proxy) processEndpointResponse(evtCtx eventContext, forwardURL string, resp *http.Response) {
 	localTime := time.Now().Format(timeLayout)
 
-	color := ansi.Color(os.Stdout)
-	outputStr := fmt.Sprintf("%s  <--  [%d] %s %s [%s]",
+	color := ansi.Color(p.cfg.Out)
+	maybeAttempts := ""
+	if evtCtx.attempts > 1 {
+		maybeAttempts = fmt.Sprintf(" (after %d attempts)", evtCtx.attempts)
+	}
+
+	maybeLatency := ""
+	if p.cfg.ShowResponseDetails {
+		maybeLatency = fmt.Sprintf(" in %s", formatLatency(evtCtx.latency))
+	}
+
+	outputStr := fmt.Sprintf("%s  <--  [%d] %s %s [%s]%s%s",
 		color.Faint(localTime),
-		ansi.ColorizeStatus(resp.StatusCode),
+		ansi.ColorizeStatusFor(resp.StatusCode, p.cfg.Out),
 		resp.Request.Method,
 		resp.Request.URL,
-		ansi.Linkify(evtCtx.event.ID, evtCtx.event.urlForEventID(), p.cfg.Log.Out),
+		ansi.Linkify(evtCtx.event.ID, evtCtx.event.urlForEventID(), p.cfg.Out),
+		maybeLatency,
+		maybeAttempts,
 	)
-	fmt.Println(outputStr)
+	fmt.Fprintln(p.cfg.Out, outputStr)
+
+	p.summary.recordResponse(evtCtx.event.Type, resp.StatusCode)
+
+	if evtCtx.failures != nil && deliveryFailed(resp.StatusCode) {
+		atomic.AddInt32(evtCtx.failures, 1)
+	}
 
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -323,6 +877,16 @@ func (p *Proxy) processEndpointResponse(evtCtx eventContext, forwardURL string,
 
 	body := truncate(string(buf), maxBodySize, true)
 
+	if p.cfg.ShowResponseDetails && (resp.StatusCode < 200 || resp.StatusCode >= 300) && len(buf) > 0 {
+		p.printResponseBody(string(buf))
+	}
+
+	if p.cfg.Journal != nil {
+		if err := p.cfg.Journal.RecordResponse(evtCtx.webhookID, forwardURL, resp.StatusCode, body); err != nil {
+			p.cfg.Log.Debugf("Failed to write endpoint response to journal: %v", err)
+		}
+	}
+
 	idx := 0
 	headers := make(map[string]string)
 
@@ -335,7 +899,22 @@ func (p *Proxy) processEndpointResponse(evtCtx eventContext, forwardURL string,
 		}
 	}
 
-	if p.webSocketClient != nil {
+	if p.cfg.Observer != nil {
+		p.cfg.Observer.EndpointResponded(&EndpointResponse{
+			Time:      time.Now(),
+			WebhookID: evtCtx.webhookID,
+			EventID:   evtCtx.event.ID,
+			EventType: evtCtx.event.Type,
+			URL:       forwardURL,
+			Status:    resp.StatusCode,
+			Body:      body,
+			Headers:   headers,
+			Latency:   evtCtx.latency,
+			Attempts:  evtCtx.attempts,
+		})
+	}
+
+	if !evtCtx.local {
 		msg := websocket.NewWebhookResponse(
 			evtCtx.webhookID,
 			evtCtx.webhookConversationID,
@@ -344,8 +923,20 @@ func (p *Proxy) processEndpointResponse(evtCtx eventContext, forwardURL string,
 			body,
 			headers,
 		)
-		p.webSocketClient.SendMessage(msg)
+		p.sendMessage(msg)
+	}
+}
+
+// sendMessage sends msg to Stripe, unless the websocket client is stopped.
+func (p *Proxy) sendMessage(msg *websocket.OutgoingMessage) {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+
+	if p.webSocketClient == nil || p.webSocketStopped {
+		return
 	}
+
+	p.webSocketClient.SendMessage(msg)
 }
 
 //
@@ -353,24 +944,51 @@ func (p *Proxy) processEndpointResponse(evtCtx eventContext, forwardURL string,
 //
 
 // New creates a new Proxy
-func New(cfg *Config, events []string) *Proxy {
+func New(cfg *Config, events []string) (*Proxy, error) {
 	if cfg.Log == nil {
 		cfg.Log = &log.Logger{Out: ioutil.Discard}
 	}
 
+	if cfg.Out == nil {
+		cfg.Out = os.Stdout
+	}
+
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+
 	p := &Proxy{
-		cfg: cfg,
+		cfg:              cfg,
+		events:           events,
+		ready:            make(chan struct{}),
+		seen:             make(map[string]int64),
+		pinnedDeliveries: newDeliveredSet(),
+		summary:          newSummary(),
+		dispatcher:       newDispatcher(cfg.MaxConcurrentDeliveries, cfg.OrderedDelivery),
 		stripeAuthClient: stripeauth.NewClient(cfg.Key, &stripeauth.Config{
 			Log:        cfg.Log,
 			APIBaseURL: cfg.APIBaseURL,
 		}),
 	}
 
-	if len(events) > 0 {
-		p.events = convertToMap(events)
-	}
+	p.deliveryCtx, p.cancelDelivery = context.WithCancel(context.Background())
 
 	for _, route := range cfg.EndpointRoutes {
+		tlsConfig, err := newTLSConfig(route.TLS, cfg.SkipVerify || route.SkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid TLS configuration for endpoint %s: %v", route.URL, err)
+		}
+
+		transport, err := newEndpointTransport(route.URL, tlsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid endpoint URL %s: %v", route.URL, err)
+		}
+
+		timeout := defaultTimeout
+		if route.Timeout > 0 {
+			timeout = route.Timeout
+		}
+
 		// append to endpointClients
 		p.endpointClients = append(p.endpointClients, NewEndpointClient(
 			route.URL,
@@ -382,18 +1000,21 @@ func New(cfg *Config, events []string) *Proxy {
 					CheckRedirect: func(req *http.Request, via []*http.Request) error {
 						return http.ErrUseLastResponse
 					},
-					Timeout: defaultTimeout,
-					Transport: &http.Transport{
-						TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.SkipVerify},
-					},
+					Timeout:   timeout,
+					Transport: transport,
 				},
 				Log:             p.cfg.Log,
+				Out:             p.cfg.Out,
 				ResponseHandler: EndpointResponseHandlerFunc(p.processEndpointResponse),
+				AttemptHandler:  EndpointAttemptHandlerFunc(p.processEndpointAttempt),
+				Retry:           route.Retry,
+				SigningSecret:   route.SigningSecret,
+				APIVersion:      route.APIVersion,
 			},
 		))
 	}
 
-	return p
+	return p, nil
 }
 
 //
@@ -404,23 +1025,92 @@ type eventContext struct {
 	webhookID             string
 	webhookConversationID string
 	event                 *stripeEvent
+
+	// attempts is the number of delivery attempts made to the endpoint
+	attempts int
+
+	// latency is the duration of the last delivery attempt
+	latency time.Duration
+
+	// local is true for events that were not delivered by Stripe (replayed,
+	// backfilled or received on the local ingress), whose responses must
+	// therefore not be reported back
+	local bool
+
+	// failures, if set, counts the failed deliveries, as defined by
+	// deliveryFailed
+	failures *int32
 }
 
+// eventSource is where an event handled by the proxy comes from.
+type eventSource int
+
+const (
+	// sourceStripe is for the events delivered by Stripe over the websocket
+	sourceStripe eventSource = iota
+
+	// sourceIngress is for the events POSTed to the local ingress
+	sourceIngress
+
+	// sourceBackfill is for the events fetched from the API after a
+	// disconnection, rendered with the API version selected by the proxy's
+	// setting
+	sourceBackfill
+
+	// sourceBackfillPinned is for the events fetched from the API after a
+	// disconnection, rendered with the API version of pinned endpoints
+	sourceBackfillPinned
+)
+
+// backfilled reports whether the events come from the API.
+func (s eventSource) backfilled() bool {
+	return s == sourceBackfill || s == sourceBackfillPinned
+}
+
+// eventOutcome is what became of an event handled by the proxy.
+type eventOutcome int
+
+const (
+	// eventForwarded is for the events dispatched to the endpoints that
+	// support them
+	eventForwarded eventOutcome = iota
+
+	// eventFiltered is for the events that don't match the listened event
+	// types or filters, or whose rendering no endpoint is pinned to
+	eventFiltered
+
+	// eventDropped is for the events that could not be forwarded, because
+	// the proxy is shutting down or the event could not be processed
+	eventDropped
+)
+
 //
 // Private constants
 //
 
 const (
-	maxBodySize        = 5000
-	maxNumHeaders      = 20
-	maxHeaderKeySize   = 50
-	maxHeaderValueSize = 200
+	defaultShutdownTimeout = 10 * time.Second
+
+	maxBodySize          = 5000
+	maxDisplayedBodySize = 1000
+	maxNumHeaders        = 20
+	maxHeaderKeySize     = 50
+	maxHeaderValueSize   = 200
 )
 
 //
 // Private functions
 //
 
+// formatLatency formats a delivery latency, e.g. 42ms or 1.50s.
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
+
 // truncate will truncate str to be less than or equal to maxByteLength bytes.
 // It will respect UTF8 and truncate the string at a code point boundary.
 // If ellipsis is true, we'll append "..." to the truncated string if the string
@@ -9,8 +9,11 @@ import (
 )
 
 func TestFilterWebhookEvent(t *testing.T) {
-	proxyUseDefault := New(&Config{UseLatestAPIVersion: false}, []string{"*"})
-	proxyUseLatest := New(&Config{UseLatestAPIVersion: true}, []string{"*"})
+	proxyUseDefault, err := New(&Config{UseLatestAPIVersion: false}, []string{"*"})
+	require.NoError(t, err)
+
+	proxyUseLatest, err := New(&Config{UseLatestAPIVersion: true}, []string{"*"})
+	require.NoError(t, err)
 
 	evtDefault := &websocket.WebhookEvent{
 		Endpoint: websocket.WebhookEndpoint{
@@ -0,0 +1,289 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stripe/stripe-cli/pkg/ansi"
+	"github.com/stripe/stripe-cli/pkg/requests"
+	"github.com/stripe/stripe-cli/pkg/stripe"
+)
+
+//
+// Private constants
+//
+
+const (
+	// maxRenewalBackoff is the longest wait between two attempts to create a
+	// new session
+	maxRenewalBackoff = time.Minute
+
+	// maxCountedMissedEvents is the number of missed events after which
+	// counting stops
+	maxCountedMissedEvents = 1000
+)
+
+//
+// Private functions
+//
+
+// renewalBackoff returns how long to wait before trying to create a new
+// session after failures consecutive failures. The first attempt is
+// immediate, then the wait doubles from 2s up to maxRenewalBackoff.
+func renewalBackoff(failures int) time.Duration {
+	if failures <= 1 {
+		return 0
+	}
+
+	if failures > 7 {
+		return maxRenewalBackoff
+	}
+
+	backoff := time.Second << uint(failures-1)
+	if backoff > maxRenewalBackoff {
+		return maxRenewalBackoff
+	}
+
+	return backoff
+}
+
+// processConnectionChange is called by the websocket client when the
+// connection is lost and when it is established again with the same session.
+func (p *Proxy) processConnectionChange(connected bool, err error) {
+	if connected {
+		// Listing the missed events may take a while, don't hold up the
+		// websocket client
+		go p.reconnected()
+		return
+	}
+
+	p.disconnected(SessionReconnecting, fmt.Sprintf("connection lost: %v", err), "Connection to Stripe lost, reconnecting...")
+}
+
+// disconnected records the start of a disconnection, if it is not already
+// under way, and reports the new status.
+func (p *Proxy) disconnected(status SessionStatus, reason string, msg string) {
+	p.connMu.Lock()
+	if p.disconnectedAt.IsZero() {
+		p.disconnectedAt = time.Now()
+	}
+	p.connMu.Unlock()
+
+	ansi.StartSpinner(p.spinner, msg, p.cfg.Log.Out)
+
+	if p.cfg.Observer != nil {
+		p.cfg.Observer.SessionStatusChanged(&SessionStatusChange{
+			Time:   time.Now(),
+			Status: status,
+			Reason: reason,
+		})
+	}
+}
+
+// reconnected reports the end of a disconnection and, when backfilling,
+// forwards the events created in the meantime. It returns false if the proxy
+// was not disconnected.
+func (p *Proxy) reconnected() bool {
+	p.connMu.Lock()
+	disconnectedAt := p.disconnectedAt
+	lastCreated := p.lastCreated
+	p.disconnectedAt = time.Time{}
+	p.connMu.Unlock()
+
+	if disconnectedAt.IsZero() {
+		return false
+	}
+
+	downtime := time.Since(disconnectedAt)
+
+	// Events created shortly before the disconnection was noticed may have
+	// been lost as well, so look from the last event received
+	since := disconnectedAt.Unix()
+	if lastCreated > 0 && lastCreated < since {
+		since = lastCreated
+	}
+
+	// Without backfilling, the events created in the meantime are not listed
+	// so as not to use the account's rate limit
+	if !p.cfg.Backfill {
+		ansi.StopSpinner(p.spinner, fmt.Sprintf("Reconnected after %s. Events sent in the meantime may have been missed", formatDowntime(downtime)), p.cfg.Log.Out)
+		p.notifyReconnected(downtime, -1)
+
+		return true
+	}
+
+	// The events are fetched with the API version the proxy forwards, which
+	// may not be known yet if it is the latest one
+	version, versionKnown := p.primaryAPIVersion()
+
+	missed, err := p.listMissedEvents(since, version)
+
+	var msg string
+
+	switch {
+	case err != nil:
+		p.cfg.Log.WithFields(log.Fields{
+			"prefix": "proxy.Proxy.reconnected",
+			"error":  err,
+		}).Debug("Failed to list the events created while disconnected")
+
+		msg = fmt.Sprintf("Reconnected after %s. Events sent in the meantime may have been missed", formatDowntime(downtime))
+	case len(missed) > 0 && !versionKnown:
+		msg = fmt.Sprintf("Reconnected after %s. %d events were created in the meantime but can't be forwarded, since the latest API version is not known yet", formatDowntime(downtime), len(missed))
+	case len(missed) > 0:
+		msg = fmt.Sprintf("Reconnected after %s. Forwarding the %d events created in the meantime", formatDowntime(downtime), len(missed))
+	default:
+		msg = fmt.Sprintf("Reconnected after %s. No events were created in the meantime", formatDowntime(downtime))
+	}
+
+	ansi.StopSpinner(p.spinner, msg, p.cfg.Log.Out)
+
+	missedEvents := len(missed)
+	if err != nil {
+		missedEvents = -1
+	}
+
+	p.notifyReconnected(downtime, missedEvents)
+
+	// All the renderings are listed before forwarding any, since forwarded
+	// events are then considered as seen
+	pinned := p.listMissedPinnedEvents(since, version)
+
+	if versionKnown {
+		p.backfill(missed, sourceBackfill)
+	}
+
+	for _, events := range pinned {
+		p.backfill(events, sourceBackfillPinned)
+	}
+
+	return true
+}
+
+// notifyReconnected notifies the observer of the end of a disconnection.
+func (p *Proxy) notifyReconnected(downtime time.Duration, missedEvents int) {
+	if p.cfg.Observer == nil {
+		return
+	}
+
+	p.cfg.Observer.SessionStatusChanged(&SessionStatusChange{
+		Time:         time.Now(),
+		Status:       SessionConnected,
+		Downtime:     downtime,
+		MissedEvents: missedEvents,
+	})
+}
+
+// primaryAPIVersion returns the API version of the events forwarded to the
+// endpoints that are not pinned to a version: "" for the account's default
+// one, or the latest one. It reports false if the latest version is needed
+// but no event rendered with it was received yet.
+func (p *Proxy) primaryAPIVersion() (string, bool) {
+	if !p.cfg.UseLatestAPIVersion {
+		return "", true
+	}
+
+	latest, _ := p.latestAPIVersion.Load().(string)
+
+	return latest, latest != ""
+}
+
+// listMissedPinnedEvents returns the events missed since the given Unix time
+// for the endpoints pinned to an API version other than primaryVersion, once
+// per version and rendered with it.
+func (p *Proxy) listMissedPinnedEvents(since int64, primaryVersion string) [][]json.RawMessage {
+	versions := make(map[string]bool)
+
+	for _, endpoint := range p.endpointClients {
+		if endpoint.cfg.APIVersion != "" && endpoint.cfg.APIVersion != primaryVersion {
+			versions[endpoint.cfg.APIVersion] = true
+		}
+	}
+
+	pinned := make([][]json.RawMessage, 0, len(versions))
+
+	for version := range versions {
+		missed, err := p.listMissedEvents(since, version)
+		if err != nil {
+			p.cfg.Log.WithFields(log.Fields{
+				"prefix":      "proxy.Proxy.listMissedPinnedEvents",
+				"api_version": version,
+				"error":       err,
+			}).Debug("Failed to list the events created while disconnected")
+
+			continue
+		}
+
+		pinned = append(pinned, missed)
+	}
+
+	return pinned
+}
+
+// listMissedEvents returns the events created since the given Unix time that
+// were not received from Stripe and would have been forwarded, oldest first,
+// rendered with the given API version (the account's default one if empty).
+// Listing stops at maxCountedMissedEvents events.
+func (p *Proxy) listMissedEvents(since int64, version string) ([]json.RawMessage, error) {
+	if p.cfg.Key == "" {
+		return nil, errors.New("no API key")
+	}
+
+	baseURL := p.cfg.APIBaseURL
+	if baseURL == "" {
+		baseURL = stripe.DefaultAPIBaseURL
+	}
+
+	missed := make([]json.RawMessage, 0)
+	startingAfter := ""
+
+	for {
+		page, err := requests.EventsList(baseURL, version, p.cfg.Key, since, startingAfter)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, raw := range page.Data {
+			var evt stripeEvent
+			if err := json.Unmarshal(raw, &evt); err != nil {
+				continue
+			}
+
+			startingAfter = evt.ID
+
+			if p.wasSeen(evt.ID) || !EventTypeMatches(p.events, evt.Type) || !p.matchesFilters(string(raw)) {
+				continue
+			}
+
+			missed = append(missed, raw)
+
+			if len(missed) >= maxCountedMissedEvents {
+				break
+			}
+		}
+
+		if !page.HasMore || len(page.Data) == 0 || len(missed) >= maxCountedMissedEvents {
+			break
+		}
+	}
+
+	// Events are listed most recent first
+	for i, j := 0, len(missed)-1; i < j; i, j = i+1, j-1 {
+		missed[i], missed[j] = missed[j], missed[i]
+	}
+
+	return missed, nil
+}
+
+// formatDowntime formats a duration to the second, e.g. 2m5s.
+func formatDowntime(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewalBackoff(t *testing.T) {
+	require.Equal(t, time.Duration(0), renewalBackoff(1))
+	require.Equal(t, 2*time.Second, renewalBackoff(2))
+	require.Equal(t, 4*time.Second, renewalBackoff(3))
+	require.Equal(t, 32*time.Second, renewalBackoff(6))
+	require.Equal(t, time.Minute, renewalBackoff(7))
+	require.Equal(t, time.Minute, renewalBackoff(100))
+}
+
+func TestReconnectedReportsMissedEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/events", r.URL.Path)
+		require.Equal(t, "Bearer sk_test_123", r.Header.Get("Authorization"))
+		require.NotEmpty(t, r.URL.Query().Get("created[gte]"))
+
+		// Two pages, of which three events match the listened types
+		if r.URL.Query().Get("starting_after") == "" {
+			fmt.Fprint(w, `{"data":[{"id":"evt_3","type":"customer.updated"},{"id":"evt_2","type":"charge.succeeded"}],"has_more":true}`)
+			return
+		}
+
+		require.Equal(t, "evt_2", r.URL.Query().Get("starting_after"))
+		fmt.Fprint(w, `{"data":[{"id":"evt_1","type":"customer.created"},{"id":"evt_0","type":"customer.created"}],"has_more":false}`)
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+
+	observer := &recordingObserver{}
+
+	p, err := New(&Config{
+		Key:        "sk_test_123",
+		APIBaseURL: ts.URL,
+		Log:        &log.Logger{Out: &out},
+		Observer:   observer,
+		Backfill:   true,
+	}, []string{"customer.*"})
+	require.NoError(t, err)
+
+	p.processConnectionChange(false, errors.New("EOF"))
+	require.True(t, p.reconnected())
+
+	// Not disconnected anymore
+	require.False(t, p.reconnected())
+
+	require.Len(t, observer.statuses, 2)
+	require.Equal(t, SessionReconnecting, observer.statuses[0].Status)
+	require.Equal(t, "connection lost: EOF", observer.statuses[0].Reason)
+	require.Equal(t, SessionConnected, observer.statuses[1].Status)
+	require.Equal(t, 3, observer.statuses[1].MissedEvents)
+
+	require.Contains(t, out.String(), "Connection to Stripe lost, reconnecting...")
+	require.Contains(t, out.String(), "Forwarding the 3 events created in the meantime")
+}
+
+func TestReconnectedWithoutBackfill(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.FailNow(t, "Events should not be listed without backfilling")
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+
+	observer := &recordingObserver{}
+
+	p, err := New(&Config{
+		Key:        "sk_test_123",
+		APIBaseURL: ts.URL,
+		Log:        &log.Logger{Out: &out},
+		Observer:   observer,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	p.processConnectionChange(false, errors.New("EOF"))
+	require.True(t, p.reconnected())
+
+	require.Equal(t, -1, observer.statuses[1].MissedEvents)
+	require.Regexp(t, `Reconnected after \S+\. Events sent in the meantime may have been missed`, out.String())
+}
+
+func TestReconnectedWithoutAPIAccess(t *testing.T) {
+	observer := &recordingObserver{}
+
+	p, err := New(&Config{Observer: observer}, []string{"*"})
+	require.NoError(t, err)
+
+	p.disconnected(SessionExpired, "session expired", "Session expired, reconnecting...")
+	require.True(t, p.reconnected())
+
+	require.Len(t, observer.statuses, 2)
+	require.Equal(t, SessionExpired, observer.statuses[0].Status)
+	require.Equal(t, -1, observer.statuses[1].MissedEvents)
+}
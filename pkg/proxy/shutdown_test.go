@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newShutdownTestProxy(t *testing.T, handler http.HandlerFunc, timeout time.Duration) (*Proxy, func()) {
+	ts := httptest.NewServer(handler)
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"*"}},
+		},
+		Out:             ioutil.Discard,
+		ShutdownTimeout: timeout,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	return p, ts.Close
+}
+
+func injectEvent(p *Proxy, payload string) int {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	rec := httptest.NewRecorder()
+
+	p.handleIngressRequest(rec, req)
+
+	return rec.Code
+}
+
+func TestShutdownWaitsForDeliveries(t *testing.T) {
+	delivered := make(chan struct{}, 1)
+
+	p, closeServer := newShutdownTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		delivered <- struct{}{}
+		w.WriteHeader(http.StatusOK)
+	}, 5*time.Second)
+	defer closeServer()
+
+	var logs bytes.Buffer
+	p.cfg.Log.Out = &logs
+
+	require.Equal(t, http.StatusAccepted, injectEvent(p, `{"id":"evt_1","type":"customer.created"}`))
+
+	require.NoError(t, p.shutdown(func() {}))
+	require.Len(t, delivered, 1)
+	require.Contains(t, logs.String(), "Waiting for 1 event deliveries in progress to complete")
+
+	// New events are refused once stopped
+	require.Equal(t, http.StatusServiceUnavailable, injectEvent(p, `{"id":"evt_2","type":"customer.created"}`))
+	require.Equal(t, 0, p.dispatcher.wait(time.Second))
+	require.Len(t, delivered, 1)
+}
+
+func TestShutdownReportsFailures(t *testing.T) {
+	p, closeServer := newShutdownTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}, 5*time.Second)
+	defer closeServer()
+
+	injectEvent(p, `{"id":"evt_1","type":"customer.created"}`)
+
+	require.EqualError(t, p.shutdown(func() {}), "1 event deliveries failed")
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+
+	p, closeServer := newShutdownTestProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}, 10*time.Millisecond)
+	defer closeServer()
+	defer close(release)
+
+	injectEvent(p, `{"id":"evt_1","type":"customer.created"}`)
+
+	require.EqualError(t, p.shutdown(func() {}), "Gave up waiting for 1 event deliveries in progress")
+}
@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Besides regular HTTP(S) URLs, events can be forwarded to the following
+// sinks:
+//
+//	exec:<command>              run a command with the event JSON on stdin
+//	file:<path>                 append the event JSON to a file, one event per line
+//	unix:<socket>[:<path>]      POST the event over a unix domain socket
+//
+// Each sink is implemented as an http.RoundTripper so that the EndpointClient
+// can treat every destination the same way, including retries, timeouts and
+// response reporting.
+const (
+	execSinkPrefix = "exec:"
+	fileSinkPrefix = "file:"
+	unixSinkPrefix = "unix:"
+)
+
+//
+// Public functions
+//
+
+// IsSinkURL reports whether url designates one of the non-HTTP sinks rather
+// than an HTTP(S) endpoint.
+func IsSinkURL(url string) bool {
+	return strings.HasPrefix(url, execSinkPrefix) ||
+		strings.HasPrefix(url, fileSinkPrefix) ||
+		strings.HasPrefix(url, unixSinkPrefix)
+}
+
+//
+// Private types
+//
+
+// execTransport runs a command for every request. The request body is passed
+// on stdin and the request headers as CGI-style environment variables (e.g.
+// HTTP_STRIPE_SIGNATURE). A zero exit code maps to a 200 response and any
+// other exit code to a 500 response. The command's combined output is used as
+// the response body.
+type execTransport struct {
+	command string
+}
+
+func (t *execTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(req.Context(), "cmd", "/C", t.command) // #nosec G204
+	} else {
+		cmd = exec.CommandContext(req.Context(), "sh", "-c", t.command) // #nosec G204
+	}
+
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), headersToEnv(req.Header)...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, err
+		}
+
+		return newSinkResponse(req, http.StatusInternalServerError, output), nil
+	}
+
+	return newSinkResponse(req, http.StatusOK, output), nil
+}
+
+// fileTransport appends the body of every request to a file, as a single
+// line of JSON.
+type fileTransport struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (t *fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Event payloads are usually pretty-printed, so compact them to keep one
+	// event per line
+	var line bytes.Buffer
+	if err := json.Compact(&line, body); err != nil {
+		line.Reset()
+		line.Write(bytes.ReplaceAll(body, []byte("\n"), []byte(" ")))
+	}
+
+	line.WriteByte('\n')
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(line.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return newSinkResponse(req, http.StatusOK, nil), nil
+}
+
+// unixTransport sends requests over HTTP to a server listening on a unix
+// domain socket.
+type unixTransport struct {
+	target    *url.URL
+	transport *http.Transport
+}
+
+func (t *unixTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target := *t.target
+
+	outReq := req.Clone(req.Context())
+	outReq.URL = &target
+
+	if outReq.Host == "" {
+		outReq.Host = "localhost"
+	}
+
+	resp, err := t.transport.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Request = req
+
+	return resp, nil
+}
+
+//
+// Private functions
+//
+
+// newEndpointTransport returns the http.RoundTripper used to deliver events
+// to endpointURL.
+func newEndpointTransport(endpointURL string, tlsConfig *tls.Config) (http.RoundTripper, error) {
+	switch {
+	case strings.HasPrefix(endpointURL, execSinkPrefix):
+		return &execTransport{command: strings.TrimPrefix(endpointURL, execSinkPrefix)}, nil
+	case strings.HasPrefix(endpointURL, fileSinkPrefix):
+		return &fileTransport{path: strings.TrimPrefix(endpointURL, fileSinkPrefix)}, nil
+	case strings.HasPrefix(endpointURL, unixSinkPrefix):
+		socket := strings.TrimPrefix(endpointURL, unixSinkPrefix)
+		path := "/"
+
+		if idx := strings.Index(socket, ":"); idx != -1 {
+			socket, path = socket[:idx], socket[idx+1:]
+		}
+
+		target, err := url.Parse("http://localhost" + path)
+		if err != nil {
+			return nil, err
+		}
+
+		return &unixTransport{
+			target: target,
+			transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		}, nil
+	default:
+		return &http.Transport{
+			TLSClientConfig: tlsConfig,
+		}, nil
+	}
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return []byte{}, nil
+	}
+	defer req.Body.Close()
+
+	return ioutil.ReadAll(req.Body)
+}
+
+func headersToEnv(headers http.Header) []string {
+	env := make([]string, 0, len(headers))
+
+	for k, v := range headers {
+		name := "HTTP_" + strings.ToUpper(strings.ReplaceAll(k, "-", "_"))
+		env = append(env, fmt.Sprintf("%s=%s", name, strings.Join(v, ", ")))
+	}
+
+	return env
+}
+
+func newSinkResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSinkURL(t *testing.T) {
+	require.True(t, IsSinkURL("exec:./worker.sh"))
+	require.True(t, IsSinkURL("file:/tmp/events.jsonl"))
+	require.True(t, IsSinkURL("unix:/tmp/app.sock"))
+
+	require.False(t, IsSinkURL("http://localhost:3000"))
+	require.False(t, IsSinkURL("localhost:3000"))
+}
+
+func TestExecSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec sink tests rely on a POSIX shell")
+	}
+
+	url := `exec:read body; echo "$HTTP_STRIPE_SIGNATURE $body"`
+
+	transport, err := newEndpointTransport(url, nil)
+	require.NoError(t, err)
+
+	rcvStatus := 0
+	rcvBody := ""
+	client := NewEndpointClient(
+		url,
+		[]string{},
+		false,
+		[]string{"*"},
+		&EndpointConfig{
+			HTTPClient: &http.Client{Transport: transport},
+			ResponseHandler: EndpointResponseHandlerFunc(func(evtCtx eventContext, forwardURL string, resp *http.Response) {
+				buf, err := ioutil.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				rcvStatus = resp.StatusCode
+				rcvBody = string(buf)
+			}),
+		},
+	)
+
+	err = client.Post(context.Background(), eventContext{event: &stripeEvent{ID: "evt_123"}}, "{}\n", map[string]string{"Stripe-Signature": "t=123,v1=hunter2"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rcvStatus)
+	require.Equal(t, "t=123,v1=hunter2 {}\n", rcvBody)
+
+	client.cfg.HTTPClient.Transport = &execTransport{command: "exit 3"}
+
+	err = client.Post(context.Background(), eventContext{event: &stripeEvent{ID: "evt_123"}}, "{}", map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, rcvStatus)
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.jsonl")
+
+	transport, err := newEndpointTransport("file:"+path, nil)
+	require.NoError(t, err)
+
+	client := NewEndpointClient("file:"+path, []string{}, false, []string{"*"}, &EndpointConfig{
+		HTTPClient: &http.Client{Transport: transport},
+	})
+
+	require.NoError(t, client.Post(context.Background(), eventContext{event: &stripeEvent{ID: "evt_1"}}, "{\n  \"id\": \"evt_1\"\n}", map[string]string{}))
+	require.NoError(t, client.Post(context.Background(), eventContext{event: &stripeEvent{ID: "evt_2"}}, "{\n  \"id\": \"evt_2\"\n}", map[string]string{}))
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "{\"id\":\"evt_1\"}\n{\"id\":\"evt_2\"}\n", string(content))
+}
+
+func TestUnixSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix domain sockets are not supported")
+	}
+
+	dir, err := ioutil.TempDir("", "sinks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "app.sock")
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/webhooks", r.URL.Path)
+		require.Equal(t, "{}", string(reqBody))
+
+		w.WriteHeader(http.StatusAccepted)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	transport, err := newEndpointTransport("unix:"+socket+":/webhooks", nil)
+	require.NoError(t, err)
+
+	rcvStatus := 0
+	rcvURL := ""
+	client := NewEndpointClient("unix:"+socket+":/webhooks", []string{}, false, []string{"*"}, &EndpointConfig{
+		HTTPClient: &http.Client{Transport: transport},
+		ResponseHandler: EndpointResponseHandlerFunc(func(evtCtx eventContext, forwardURL string, resp *http.Response) {
+			rcvStatus = resp.StatusCode
+			rcvURL = resp.Request.URL.String()
+		}),
+	})
+
+	require.NoError(t, client.Post(context.Background(), eventContext{event: &stripeEvent{ID: "evt_1"}}, "{}", map[string]string{}))
+	require.Equal(t, http.StatusAccepted, rcvStatus)
+	require.Equal(t, "unix:"+socket+":/webhooks", rcvURL)
+}
@@ -1,6 +1,10 @@
 package proxy
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/stripe/stripe-cli/pkg/stripe"
+)
 
 //
 // Private types
@@ -9,35 +13,37 @@ import "fmt"
 // stripeEvent is a minimal representation of a Stripe `event` object, used
 // to extract the event's ID and type for logging purposes.
 type stripeEvent struct {
-	Account  string `json:"account"`
-	ID       string `json:"id"`
-	Livemode bool   `json:"livemode"`
-	Type     string `json:"type"`
-	Created  int    `json:"created"`
+	Account    string `json:"account"`
+	APIVersion string `json:"api_version"`
+	ID         string `json:"id"`
+	Livemode   bool   `json:"livemode"`
+	Type       string `json:"type"`
+	Created    int    `json:"created"`
+	Data       struct {
+		Object struct {
+			ID string `json:"id"`
+		} `json:"object"`
+	} `json:"data"`
 }
 
 func (e *stripeEvent) isConnect() bool {
 	return e.Account != ""
 }
 
-func (e *stripeEvent) urlForEventID() string {
-	return fmt.Sprintf("%s/events/%s", baseDashboardURL(e.Livemode, e.Account), e.ID)
-}
+// objectID returns the ID of the object the event is about, or the event's own
+// ID if the payload doesn't include one.
+func (e *stripeEvent) objectID() string {
+	if e.Data.Object.ID != "" {
+		return e.Data.Object.ID
+	}
 
-func (e *stripeEvent) urlForEventType() string {
-	return fmt.Sprintf("%s/events?type=%s", baseDashboardURL(e.Livemode, e.Account), e.Type)
+	return e.ID
 }
 
-func baseDashboardURL(livemode bool, account string) string {
-	maybeTest := ""
-	if !livemode {
-		maybeTest = "/test"
-	}
-
-	maybeAccount := ""
-	if account != "" {
-		maybeAccount = fmt.Sprintf("/%s", account)
-	}
+func (e *stripeEvent) urlForEventID() string {
+	return fmt.Sprintf("%s/events/%s", stripe.DashboardURL(e.Livemode, e.Account), e.ID)
+}
 
-	return fmt.Sprintf("https://dashboard.stripe.com%s%s", maybeAccount, maybeTest)
+func (e *stripeEvent) urlForEventType() string {
+	return fmt.Sprintf("%s/events?type=%s", stripe.DashboardURL(e.Livemode, e.Account), e.Type)
 }
@@ -41,3 +41,11 @@ func TestURLForEventType(t *testing.T) {
 	evt = &stripeEvent{ID: "evt_123", Livemode: true, Type: "customer.created", Account: "acct_123"}
 	require.Equal(t, "https://dashboard.stripe.com/acct_123/events?type=customer.created", evt.urlForEventType())
 }
+
+func TestObjectID(t *testing.T) {
+	evt := &stripeEvent{ID: "evt_123"}
+	require.Equal(t, "evt_123", evt.objectID())
+
+	evt.Data.Object.ID = "cus_123"
+	require.Equal(t, "cus_123", evt.objectID())
+}
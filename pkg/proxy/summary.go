@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//
+// Private types
+//
+
+// summary counts the events received during a session and the outcome of
+// their delivery to the local endpoints, by event type and status class.
+type summary struct {
+	mu sync.Mutex
+
+	received map[string]int
+	outcomes map[string]map[string]int
+	failed   int
+}
+
+func newSummary() *summary {
+	return &summary{
+		received: make(map[string]int),
+		outcomes: make(map[string]map[string]int),
+	}
+}
+
+func (s *summary) recordEvent(eventType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.received[eventType]++
+}
+
+// recordResponse records the outcome of delivering an event to an endpoint.
+// A status of 0 means that the endpoint could not be reached.
+func (s *summary) recordResponse(eventType string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.outcomes[eventType] == nil {
+		s.outcomes[eventType] = make(map[string]int)
+	}
+
+	s.outcomes[eventType][statusClass(status)]++
+
+	if deliveryFailed(status) {
+		s.failed++
+	}
+}
+
+// failures returns the number of failed deliveries, as defined by
+// deliveryFailed.
+func (s *summary) failures() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.failed
+}
+
+// print writes the summary as a table, with one line per event type. Nothing
+// is written if no event was received.
+func (s *summary) print(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.received) == 0 {
+		return
+	}
+
+	eventTypes := make([]string, 0, len(s.received))
+	width := len("EVENT TYPE")
+
+	for eventType := range s.received {
+		eventTypes = append(eventTypes, eventType)
+
+		if len(eventType) > width {
+			width = len(eventType)
+		}
+	}
+
+	sort.Strings(eventTypes)
+
+	fmt.Fprintf(w, "\n%-*s  %8s  %s\n", width, "EVENT TYPE", "RECEIVED", "RESPONSES")
+
+	total := 0
+
+	for _, eventType := range eventTypes {
+		total += s.received[eventType]
+
+		outcomes := make([]string, 0, len(statusClasses))
+		for _, class := range statusClasses {
+			if n := s.outcomes[eventType][class]; n > 0 {
+				outcomes = append(outcomes, fmt.Sprintf("%s: %d", class, n))
+			}
+		}
+
+		fmt.Fprintf(w, "%-*s  %8d  %s\n", width, eventType, s.received[eventType], strings.Join(outcomes, ", "))
+	}
+
+	fmt.Fprintf(w, "%-*s  %8d\n", width, "Total", total)
+}
+
+//
+// Private variables
+//
+
+var statusClasses = []string{"2xx", "3xx", "4xx", "5xx", "failed"}
+
+//
+// Private functions
+//
+
+// deliveryFailed returns whether a delivery that got a response with the
+// given status failed, and should make `listen` exit with a non-zero status.
+// Deliveries fail when the endpoint could not be reached (status 0) or
+// returned a 4xx or 5xx error. Redirects, which aren't followed, aren't
+// failures: the endpoint did handle the request.
+func deliveryFailed(status int) bool {
+	return status < 200 || status >= 400
+}
+
+func statusClass(status int) string {
+	switch {
+	case status >= 200 && status < 600:
+		return fmt.Sprintf("%dxx", status/100)
+	default:
+		return "failed"
+	}
+}
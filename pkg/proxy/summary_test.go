@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummary(t *testing.T) {
+	s := newSummary()
+
+	var empty bytes.Buffer
+	s.print(&empty)
+	require.Empty(t, empty.String())
+
+	s.recordEvent("customer.created")
+	s.recordEvent("customer.created")
+	s.recordEvent("charge.succeeded")
+	s.recordResponse("customer.created", 200)
+	s.recordResponse("customer.created", 503)
+	s.recordResponse("charge.succeeded", 0)
+
+	var out bytes.Buffer
+	s.print(&out)
+
+	require.Equal(t, `
+EVENT TYPE        RECEIVED  RESPONSES
+charge.succeeded         1  failed: 1
+customer.created         2  2xx: 1, 5xx: 1
+Total                    3
+`, out.String())
+}
+
+func TestStatusClass(t *testing.T) {
+	require.Equal(t, "2xx", statusClass(204))
+	require.Equal(t, "4xx", statusClass(400))
+	require.Equal(t, "5xx", statusClass(599))
+	require.Equal(t, "failed", statusClass(0))
+}
+
+func TestSummaryFailures(t *testing.T) {
+	s := newSummary()
+
+	s.recordResponse("customer.created", 200)
+	s.recordResponse("customer.created", 302)
+	require.Equal(t, 0, s.failures())
+
+	s.recordResponse("customer.created", 404)
+	s.recordResponse("customer.created", 500)
+	s.recordResponse("customer.created", 0)
+	require.Equal(t, 3, s.failures())
+}
+
+func TestShowResponseDetails(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Missing order_id\nin metadata"))
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"*"}},
+		},
+		Out:                 &out,
+		ShowResponseDetails: true,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	p.Replay([]*JournalEntry{
+		{
+			WebhookID:    "wh_123",
+			EventPayload: `{"id":"evt_123","type":"customer.created"}`,
+		},
+	})
+
+	require.Regexp(t, `<--  \[400\] POST .* \[evt_123\] in \d+ms`, out.String())
+	require.Contains(t, out.String(), responseBodyIndent+"Missing order_id\n")
+	require.Contains(t, out.String(), responseBodyIndent+"in metadata\n")
+}
+
+func TestFormatLatency(t *testing.T) {
+	require.Equal(t, "42ms", formatLatency(42*time.Millisecond))
+	require.Equal(t, "1.50s", formatLatency(1500*time.Millisecond))
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+//
+// Public types
+//
+
+// TLSConfig configures the TLS connections to an HTTPS endpoint, e.g. one
+// that sits behind mutual TLS with a development certificate authority.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of certificate authorities to trust, in
+	// addition to the system's, when verifying the endpoint's certificate
+	CAFile string
+
+	// CertFile and KeyFile are the PEM client certificate and private key to
+	// present to endpoints that require client authentication
+	CertFile string
+	KeyFile  string
+
+	// ServerName, if set, is sent as SNI and used to verify the endpoint's
+	// certificate instead of the host of the endpoint's URL
+	ServerName string
+}
+
+//
+// Private functions
+//
+
+// newTLSConfig builds the TLS configuration used to connect to an endpoint.
+func newTLSConfig(cfg TLSConfig, skipVerify bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: skipVerify,
+		ServerName:         cfg.ServerName,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA bundle: %v", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", cfg.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("a client certificate and its key must be specified together")
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %v", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeClientCertificate generates a self-signed client certificate and
+// writes it and its key to dir.
+func writeClientCertificate(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "stripe-cli"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return cert, certFile, keyFile
+}
+
+func TestForwardWithMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	clientCert, certFile, keyFile := writeClientCertificate(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "example.com", r.TLS.ServerName)
+		require.Equal(t, "stripe-cli", r.TLS.PeerCertificates[0].Subject.CommonName)
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	// The test server's certificate is valid for example.com
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600))
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{
+				URL:        ts.URL,
+				EventTypes: []string{"*"},
+				TLS: TLSConfig{
+					CAFile:     caFile,
+					CertFile:   certFile,
+					KeyFile:    keyFile,
+					ServerName: "example.com",
+				},
+			},
+		},
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	evtCtx := eventContext{event: &stripeEvent{ID: "evt_123", Type: "customer.created"}, local: true}
+	require.NoError(t, p.endpointClients[0].Post(context.Background(), evtCtx, `{"id":"evt_123"}`, map[string]string{}))
+
+	// Without the client certificate, the handshake fails
+	p, err = New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"*"}, TLS: TLSConfig{CAFile: caFile, ServerName: "example.com"}},
+		},
+	}, []string{"*"})
+	require.NoError(t, err)
+	require.Error(t, p.endpointClients[0].Post(context.Background(), evtCtx, `{"id":"evt_123"}`, map[string]string{}))
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	_, err := newTLSConfig(TLSConfig{CAFile: "does-not-exist.pem"}, false)
+	require.Error(t, err)
+
+	_, err = newTLSConfig(TLSConfig{CertFile: "client.pem"}, false)
+	require.EqualError(t, err, "a client certificate and its key must be specified together")
+
+	_, err = New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: "https://localhost:5000", TLS: TLSConfig{CAFile: "does-not-exist.pem"}},
+		},
+	}, []string{"*"})
+	require.Error(t, err)
+}
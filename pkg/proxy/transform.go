@@ -0,0 +1,430 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-cli/pkg/ansi"
+	"github.com/stripe/stripe-cli/pkg/webhooks"
+)
+
+//
+// Public types
+//
+
+// ForwardedEvent is an event on its way to the local endpoints, as seen by
+// transforms.
+type ForwardedEvent struct {
+	// Payload is the JSON body of the event
+	Payload string
+
+	// Headers are the HTTP headers sent with the event
+	Headers map[string]string
+}
+
+// Transform modifies events before they are forwarded to the local
+// endpoints. When a transform changes the payload, the event is re-signed so
+// that signature checks still pass.
+type Transform interface {
+	Apply(evt *ForwardedEvent) error
+}
+
+// TransformFunc is an adapter to allow the use of ordinary functions as
+// transforms.
+type TransformFunc func(evt *ForwardedEvent) error
+
+// Apply calls f(evt).
+func (f TransformFunc) Apply(evt *ForwardedEvent) error {
+	return f(evt)
+}
+
+// TransformRule is a declarative transform. Exactly one of Set, Delete,
+// Header or Replace must be specified.
+//
+// Paths are dot-separated keys into the payload, where numeric keys index
+// arrays (e.g. data.object.items.data.0.price). Values may reference other
+// fields of the payload as ${path}, e.g. ${account}.
+type TransformRule struct {
+	// Set is the path of a payload field to set to Value, which is inserted
+	// as a string unless JSONValue is set. Missing parent objects are
+	// created.
+	Set string
+
+	// Delete is the path of a payload field to remove
+	Delete string
+
+	// Header is the name of an HTTP header to set to Value
+	Header string
+
+	// Replace is a string to replace with With in all the string values of
+	// the payload, or only in those under Path if specified
+	Replace string
+	With    string
+	Path    string
+
+	Value string
+
+	// JSONValue means that Value is JSON (e.g. a number, a boolean or an
+	// object) to be inserted as such by Set
+	JSONValue bool
+}
+
+// RuleTransform applies a list of declarative rules, in order.
+type RuleTransform struct {
+	rules []TransformRule
+}
+
+// Apply applies the rules to evt.
+func (t *RuleTransform) Apply(evt *ForwardedEvent) error {
+	var payload interface{}
+
+	decoder := json.NewDecoder(strings.NewReader(evt.Payload))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&payload); err != nil {
+		return fmt.Errorf("event payload is not valid JSON: %v", err)
+	}
+
+	modified := false
+
+	for _, rule := range t.rules {
+		switch {
+		case rule.Set != "":
+			var value interface{} = interpolate(rule.Value, payload)
+
+			if rule.JSONValue {
+				var err error
+
+				value, err = decodeJSONValue(value.(string))
+				if err != nil {
+					return fmt.Errorf("cannot set %s: value is not valid JSON: %v", rule.Set, err)
+				}
+			}
+
+			var err error
+
+			payload, err = setPath(payload, splitPath(rule.Set), value)
+			if err != nil {
+				return fmt.Errorf("cannot set %s: %v", rule.Set, err)
+			}
+
+			modified = true
+		case rule.Delete != "":
+			if deletePath(payload, splitPath(rule.Delete)) {
+				modified = true
+			}
+		case rule.Header != "":
+			evt.Headers[rule.Header] = interpolate(rule.Value, payload)
+		case rule.Replace != "":
+			if rule.Path == "" {
+				var replaced bool
+
+				payload, replaced = replaceStrings(payload, rule.Replace, rule.With)
+				modified = modified || replaced
+			} else if value, ok := getPath(payload, splitPath(rule.Path)); ok {
+				value, replaced := replaceStrings(value, rule.Replace, rule.With)
+				if !replaced {
+					continue
+				}
+
+				var err error
+
+				payload, err = setPath(payload, splitPath(rule.Path), value)
+				if err != nil {
+					return fmt.Errorf("cannot replace in %s: %v", rule.Path, err)
+				}
+
+				modified = true
+			}
+		}
+	}
+
+	if !modified {
+		return nil
+	}
+
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(payload); err != nil {
+		return err
+	}
+
+	evt.Payload = strings.TrimSuffix(buf.String(), "\n")
+
+	return nil
+}
+
+//
+// Public functions
+//
+
+// NewRuleTransform returns a transform applying the given rules, in order.
+func NewRuleTransform(rules []TransformRule) (*RuleTransform, error) {
+	for i, rule := range rules {
+		actions := 0
+
+		for _, field := range []string{rule.Set, rule.Delete, rule.Header, rule.Replace} {
+			if field != "" {
+				actions++
+			}
+		}
+
+		if actions != 1 {
+			return nil, fmt.Errorf("rule #%d must specify exactly one of set, delete, header or replace", i+1)
+		}
+	}
+
+	return &RuleTransform{rules: rules}, nil
+}
+
+//
+// Private variables
+//
+
+var interpolationRegexp = regexp.MustCompile(`\$\{([^{}]+)\}`)
+
+//
+// Private functions
+//
+
+// applyTransforms runs the configured transforms on an event's payload and
+// headers. The returned headers are a copy, so that the original event is
+// left untouched. If the payload was modified, the event is re-signed with
+// the session's signing secret.
+func (p *Proxy) applyTransforms(payload string, headers map[string]string) (string, map[string]string, error) {
+	evt := &ForwardedEvent{
+		Payload: payload,
+		Headers: make(map[string]string, len(headers)),
+	}
+
+	for k, v := range headers {
+		evt.Headers[k] = v
+	}
+
+	for _, transform := range p.cfg.Transforms {
+		if err := transform.Apply(evt); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if evt.Payload != payload {
+		p.resign(evt)
+	}
+
+	return evt.Payload, evt.Headers, nil
+}
+
+// transformEvent applies the configured transforms to an event. Failures are
+// printed, and reported as false.
+func (p *Proxy) transformEvent(evt *stripeEvent, payload string, headers map[string]string) (string, map[string]string, bool) {
+	if len(p.cfg.Transforms) == 0 {
+		return payload, headers, true
+	}
+
+	payload, headers, err := p.applyTransforms(payload, headers)
+	if err != nil {
+		color := ansi.Color(p.cfg.Out)
+		localTime := time.Now().Format(timeLayout)
+
+		fmt.Fprintf(p.cfg.Out, "%s            [%s] Failed to transform event %s, not forwarding it: %v\n",
+			color.Faint(localTime),
+			color.Red("ERROR"),
+			evt.ID,
+			err,
+		)
+
+		return "", nil, false
+	}
+
+	return payload, headers, true
+}
+
+// resign replaces the signature of a transformed event, since the one
+// computed by Stripe no longer matches its payload. Endpoints that have their
+// own signing secret re-sign every event anyway.
+func (p *Proxy) resign(evt *ForwardedEvent) {
+	secret, _ := p.sessionSecret.Load().(string)
+	if secret == "" {
+		p.cfg.Log.Debug("No session secret to re-sign the transformed event with")
+		return
+	}
+
+	evt.Headers[webhooks.SignatureHeader] = webhooks.GenerateSignatureHeader(time.Now(), []byte(evt.Payload), secret)
+}
+
+func splitPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+func getPath(node interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			child, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+
+			node = child
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+
+			node = v[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return node, true
+}
+
+// setPath sets the value at path, creating missing objects along the way, and
+// returns the updated node.
+func setPath(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	key := path[0]
+
+	switch v := node.(type) {
+	case map[string]interface{}:
+		child, err := setPath(v[key], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		v[key] = child
+
+		return v, nil
+	case []interface{}:
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 || idx >= len(v) {
+			return nil, fmt.Errorf("invalid array index %s", key)
+		}
+
+		child, err := setPath(v[idx], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		v[idx] = child
+
+		return v, nil
+	case nil:
+		child, err := setPath(nil, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{key: child}, nil
+	default:
+		return nil, errors.New("parent is not an object")
+	}
+}
+
+// deletePath removes the value at path and reports whether it existed.
+func deletePath(node interface{}, path []string) bool {
+	parent, ok := getPath(node, path[:len(path)-1])
+	if !ok {
+		return false
+	}
+
+	key := path[len(path)-1]
+
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := v[key]; !ok {
+			return false
+		}
+
+		delete(v, key)
+
+		return true
+	default:
+		return false
+	}
+}
+
+// replaceStrings replaces old with new in the string values of node, and
+// reports whether any of them changed.
+func replaceStrings(node interface{}, old, new string) (interface{}, bool) {
+	replaced := false
+
+	switch v := node.(type) {
+	case string:
+		replacedString := strings.ReplaceAll(v, old, new)
+
+		return replacedString, replacedString != v
+	case map[string]interface{}:
+		for key, child := range v {
+			var childReplaced bool
+
+			v[key], childReplaced = replaceStrings(child, old, new)
+			replaced = replaced || childReplaced
+		}
+	case []interface{}:
+		for i, child := range v {
+			var childReplaced bool
+
+			v[i], childReplaced = replaceStrings(child, old, new)
+			replaced = replaced || childReplaced
+		}
+	}
+
+	return node, replaced
+}
+
+// decodeJSONValue decodes s, which must be a single JSON value.
+func decodeJSONValue(s string) (interface{}, error) {
+	var value interface{}
+
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the value")
+	}
+
+	return value, nil
+}
+
+// interpolate replaces the ${path} references in s with the corresponding
+// payload values.
+func interpolate(s string, payload interface{}) string {
+	return interpolationRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		value, ok := getPath(payload, splitPath(ref[2:len(ref)-1]))
+		if !ok || value == nil {
+			return ""
+		}
+
+		if str, ok := value.(string); ok {
+			return str
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return ""
+		}
+
+		return string(encoded)
+	})
+}
@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/webhooks"
+)
+
+const transformTestPayload = `{
+  "id": "evt_123",
+  "type": "checkout.session.completed",
+  "account": "acct_123",
+  "data": {
+    "object": {
+      "id": "cs_123",
+      "amount_total": 1000,
+      "success_url": "https://shop.example.com/success",
+      "customer_details": {
+        "email": "jenny@example.com"
+      },
+      "line_items": [
+        {"url": "https://shop.example.com/items/1"}
+      ]
+    }
+  }
+}`
+
+func TestRuleTransform(t *testing.T) {
+	transform, err := NewRuleTransform([]TransformRule{
+		{Replace: "https://shop.example.com", With: "http://localhost:3000", Path: "data.object"},
+		{Header: "X-Tenant", Value: "tenant-${account}"},
+		{Set: "data.object.metadata.env", Value: "dev"},
+		{Set: "data.object.amount_total", Value: "2000", JSONValue: true},
+		{Delete: "data.object.customer_details"},
+	})
+	require.NoError(t, err)
+
+	evt := &ForwardedEvent{
+		Payload: transformTestPayload,
+		Headers: map[string]string{},
+	}
+
+	require.NoError(t, transform.Apply(evt))
+
+	require.Equal(t, "tenant-acct_123", evt.Headers["X-Tenant"])
+	require.JSONEq(t, `{
+  "id": "evt_123",
+  "type": "checkout.session.completed",
+  "account": "acct_123",
+  "data": {
+    "object": {
+      "id": "cs_123",
+      "amount_total": 2000,
+      "success_url": "http://localhost:3000/success",
+      "metadata": {"env": "dev"},
+      "line_items": [
+        {"url": "http://localhost:3000/items/1"}
+      ]
+    }
+  }
+}`, evt.Payload)
+}
+
+func TestRuleTransformUnmodified(t *testing.T) {
+	transform, err := NewRuleTransform([]TransformRule{
+		{Delete: "data.object.missing"},
+	})
+	require.NoError(t, err)
+
+	evt := &ForwardedEvent{Payload: transformTestPayload, Headers: map[string]string{}}
+
+	require.NoError(t, transform.Apply(evt))
+	require.Equal(t, transformTestPayload, evt.Payload)
+}
+
+func TestRuleTransformLiteralValues(t *testing.T) {
+	transform, err := NewRuleTransform([]TransformRule{
+		{Set: "data.object.address", Value: "42 Main Street"},
+		{Set: "data.object.zip", Value: "02134"},
+		{Set: "data.object.label", Value: "${data.object.amount_total} cents"},
+		{Set: "data.object.flag", Value: "true"},
+	})
+	require.NoError(t, err)
+
+	evt := &ForwardedEvent{Payload: transformTestPayload, Headers: map[string]string{}}
+
+	require.NoError(t, transform.Apply(evt))
+	require.Contains(t, evt.Payload, `"address": "42 Main Street"`)
+	require.Contains(t, evt.Payload, `"zip": "02134"`)
+	require.Contains(t, evt.Payload, `"label": "1000 cents"`)
+	require.Contains(t, evt.Payload, `"flag": "true"`)
+}
+
+func TestRuleTransformInvalidJSONValue(t *testing.T) {
+	transform, err := NewRuleTransform([]TransformRule{
+		{Set: "data.object.amount_total", Value: "42 Main Street", JSONValue: true},
+	})
+	require.NoError(t, err)
+
+	evt := &ForwardedEvent{Payload: transformTestPayload, Headers: map[string]string{}}
+
+	require.Error(t, transform.Apply(evt))
+}
+
+func TestRuleTransformReplaceWithoutMatch(t *testing.T) {
+	transform, err := NewRuleTransform([]TransformRule{
+		{Replace: "https://other.example.com", With: "http://localhost:3000"},
+		{Replace: "https://other.example.com", With: "http://localhost:3000", Path: "data.object"},
+	})
+	require.NoError(t, err)
+
+	evt := &ForwardedEvent{Payload: transformTestPayload, Headers: map[string]string{}}
+
+	require.NoError(t, transform.Apply(evt))
+	require.Equal(t, transformTestPayload, evt.Payload)
+}
+
+func TestNewRuleTransformInvalid(t *testing.T) {
+	_, err := NewRuleTransform([]TransformRule{
+		{Set: "data.object.id", Delete: "data.object.id"},
+	})
+	require.EqualError(t, err, "rule #1 must specify exactly one of set, delete, header or replace")
+
+	_, err = NewRuleTransform([]TransformRule{{Value: "foo"}})
+	require.Error(t, err)
+}
+
+func TestApplyTransformsResigns(t *testing.T) {
+	p, err := New(&Config{
+		Transforms: []Transform{
+			TransformFunc(func(evt *ForwardedEvent) error {
+				evt.Payload = `{"id":"evt_123","type":"customer.updated"}`
+				return nil
+			}),
+		},
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	p.sessionSecret.Store("whsec_test_secret")
+
+	original := map[string]string{"Stripe-Signature": "t=123,v1=hunter2"}
+
+	payload, headers, err := p.applyTransforms(`{"id":"evt_123","type":"customer.created"}`, original)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"evt_123","type":"customer.updated"}`, payload)
+
+	result, err := webhooks.VerifySignatureHeader([]byte(payload), headers["Stripe-Signature"], "whsec_test_secret", webhooks.DefaultTolerance, time.Now())
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+
+	// The original headers are left untouched
+	require.Equal(t, "t=123,v1=hunter2", original["Stripe-Signature"])
+}
+
+func TestTransformFailure(t *testing.T) {
+	received := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: ts.URL, EventTypes: []string{"*"}},
+		},
+		Transforms: []Transform{
+			TransformFunc(func(evt *ForwardedEvent) error {
+				return errors.New("boom")
+			}),
+		},
+		Out: ioutil.Discard,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	p.Replay([]*JournalEntry{
+		{WebhookID: "wh_123", EventPayload: `{"id":"evt_123","type":"customer.created"}`},
+	})
+
+	require.Equal(t, 0, received)
+}
@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+//
+// Private types
+//
+
+// deliveredSet remembers which events were recently delivered to the
+// endpoints pinned to an API version. Stripe may send the same event rendered
+// with the same version twice (when the account's default version is also the
+// latest), and pinned endpoints must only receive it once.
+type deliveredSet struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+// add records key and reports whether it was not already recorded.
+func (s *deliveredSet) add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	if len(s.keys) >= maxDeliveredSetSize {
+		for k, t := range s.keys {
+			if now.Sub(t) > deliveredSetTTL {
+				delete(s.keys, k)
+			}
+		}
+	}
+
+	if _, ok := s.keys[key]; ok {
+		return false
+	}
+
+	s.keys[key] = now
+
+	return true
+}
+
+//
+// Private constants
+//
+
+const (
+	// deliveredSetTTL is how long events delivered to pinned endpoints are
+	// remembered, which must exceed the time between the deliveries of the
+	// different renderings of an event
+	deliveredSetTTL = 10 * time.Minute
+
+	// maxDeliveredSetSize is the size from which expired entries are pruned
+	maxDeliveredSetSize = 1000
+)
+
+//
+// Private functions
+//
+
+func newDeliveredSet() *deliveredSet {
+	return &deliveredSet{keys: make(map[string]time.Time)}
+}
+
+// eventAPIVersion returns the API version an event was rendered with: the one
+// requested for the delivery if any, otherwise the one of the payload, which
+// is the account's default version.
+func eventAPIVersion(deliveryVersion *string, evt *stripeEvent) string {
+	if deliveryVersion != nil {
+		return *deliveryVersion
+	}
+
+	return evt.APIVersion
+}
+
+// hasPinnedEndpoint reports whether an endpoint is pinned to the given API
+// version.
+func (p *Proxy) hasPinnedEndpoint(version string) bool {
+	for _, endpoint := range p.endpointClients {
+		if endpoint.cfg.APIVersion != "" && endpoint.cfg.APIVersion == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPrimaryAPIVersion reports whether version is the one of the events
+// forwarded to the endpoints that are not pinned to a version, i.e. the
+// account's default version or the latest one. It reports true while that
+// version is not known yet.
+func (p *Proxy) isPrimaryAPIVersion(version string) bool {
+	var primary string
+
+	if p.cfg.UseLatestAPIVersion {
+		primary, _ = p.latestAPIVersion.Load().(string)
+	} else {
+		primary, _ = p.defaultAPIVersion.Load().(string)
+	}
+
+	return primary == "" || primary == version
+}
+
+// checkPinnedAPIVersions warns about the endpoints pinned to an API version
+// that is neither the account's default one nor the latest one, since Stripe
+// never renders events with it. It only checks once both versions are known.
+func (p *Proxy) checkPinnedAPIVersions() {
+	defaultVersion, _ := p.defaultAPIVersion.Load().(string)
+	latestVersion, _ := p.latestAPIVersion.Load().(string)
+
+	if defaultVersion == "" || latestVersion == "" {
+		return
+	}
+
+	p.pinnedVersionsChecked.Do(func() {
+		for _, endpoint := range p.endpointClients {
+			version := endpoint.cfg.APIVersion
+			if version == "" || version == defaultVersion || version == latestVersion {
+				continue
+			}
+
+			fmt.Fprintf(p.cfg.Log.Out, "Warning: %s is pinned to API version %s and won't receive any events, since Stripe only sends them with versions %s (account default) and %s (latest)\n",
+				endpoint.URL,
+				version,
+				defaultVersion,
+				latestVersion,
+			)
+		}
+	})
+}
+
+// selectEndpoints returns the endpoints an event should be forwarded to.
+// Endpoints pinned to an API version receive the renderings of events with
+// that version, once per event if dedupe is set. The other endpoints receive
+// the rendering selected by the proxy's API version setting, the primary one.
+func (p *Proxy) selectEndpoints(evt *stripeEvent, version string, primary bool, dedupe bool) []*EndpointClient {
+	endpoints := make([]*EndpointClient, 0)
+
+	for _, endpoint := range p.endpointClients {
+		if !endpoint.SupportsEventType(evt.isConnect(), evt.Type) {
+			continue
+		}
+
+		if endpoint.cfg.APIVersion == "" {
+			if primary {
+				endpoints = append(endpoints, endpoint)
+			}
+
+			continue
+		}
+
+		if !endpoint.SupportsAPIVersion(version) {
+			continue
+		}
+
+		if !dedupe || p.pinnedDeliveries.add(endpoint.URL+" "+evt.ID) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	return endpoints
+}
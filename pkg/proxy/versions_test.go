@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/websocket"
+)
+
+// versionRecorder is an endpoint that records the API versions of the events
+// it receives.
+type versionRecorder struct {
+	mu       sync.Mutex
+	versions []string
+}
+
+func (r *versionRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+
+	var evt stripeEvent
+	json.Unmarshal(body, &evt) //nolint:errcheck
+
+	r.mu.Lock()
+	r.versions = append(r.versions, evt.APIVersion)
+	r.mu.Unlock()
+}
+
+// deliverRenderings simulates Stripe delivering an event rendered with the
+// account's default API version, then with the latest one.
+func deliverRenderings(p *Proxy, id, defaultVersion, latestVersion string) {
+	p.processWebhookEvent(websocket.IncomingMessage{
+		WebhookEvent: &websocket.WebhookEvent{
+			EventPayload: `{"id":"` + id + `","type":"customer.created","api_version":"` + defaultVersion + `"}`,
+			WebhookID:    "wh_default",
+		},
+	})
+	p.processWebhookEvent(websocket.IncomingMessage{
+		WebhookEvent: &websocket.WebhookEvent{
+			Endpoint:     websocket.WebhookEndpoint{APIVersion: &latestVersion},
+			EventPayload: `{"id":"` + id + `","type":"customer.created","api_version":"` + latestVersion + `"}`,
+			WebhookID:    "wh_latest",
+		},
+	})
+}
+
+func TestPinnedAPIVersion(t *testing.T) {
+	unpinned := &versionRecorder{}
+	unpinnedServer := httptest.NewServer(unpinned)
+	defer unpinnedServer.Close()
+
+	pinned := &versionRecorder{}
+	pinnedServer := httptest.NewServer(pinned)
+	defer pinnedServer.Close()
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: unpinnedServer.URL, EventTypes: []string{"*"}},
+			{URL: pinnedServer.URL, EventTypes: []string{"*"}, APIVersion: "2020-08-27"},
+		},
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	deliverRenderings(p, "evt_1", "2019-05-16", "2020-08-27")
+
+	// When the default version is also the latest, the pinned endpoint
+	// still receives the event once
+	deliverRenderings(p, "evt_2", "2020-08-27", "2020-08-27")
+
+	p.dispatcher.close()
+	require.Equal(t, 0, p.dispatcher.wait(time.Second))
+
+	require.ElementsMatch(t, []string{"2019-05-16", "2020-08-27"}, unpinned.versions)
+	require.Equal(t, []string{"2020-08-27", "2020-08-27"}, pinned.versions)
+}
+
+func TestPinnedAPIVersionWithLatest(t *testing.T) {
+	pinned := &versionRecorder{}
+	pinnedServer := httptest.NewServer(pinned)
+	defer pinnedServer.Close()
+
+	unpinned := &versionRecorder{}
+	unpinnedServer := httptest.NewServer(unpinned)
+	defer unpinnedServer.Close()
+
+	// Pinning to the account's default version while the other endpoints
+	// receive the latest one
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: pinnedServer.URL, EventTypes: []string{"*"}, APIVersion: "2019-05-16"},
+			{URL: unpinnedServer.URL, EventTypes: []string{"*"}},
+		},
+		UseLatestAPIVersion: true,
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	deliverRenderings(p, "evt_1", "2019-05-16", "2020-08-27")
+
+	p.dispatcher.close()
+	require.Equal(t, 0, p.dispatcher.wait(time.Second))
+
+	require.Equal(t, []string{"2019-05-16"}, pinned.versions)
+	require.Equal(t, []string{"2020-08-27"}, unpinned.versions)
+}
+
+func TestUnreachablePinnedAPIVersion(t *testing.T) {
+	var out bytes.Buffer
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: "http://localhost:3000/default", EventTypes: []string{"invoice.paid"}, APIVersion: "2019-05-16"},
+			{URL: "http://localhost:3000/old", EventTypes: []string{"invoice.paid"}, APIVersion: "2017-08-15"},
+		},
+		Log: &log.Logger{Out: &out},
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	deliverRenderings(p, "evt_1", "2019-05-16", "2020-08-27")
+	deliverRenderings(p, "evt_2", "2019-05-16", "2020-08-27")
+
+	require.Equal(t, "Warning: http://localhost:3000/old is pinned to API version 2017-08-15 and won't receive any events, "+
+		"since Stripe only sends them with versions 2019-05-16 (account default) and 2020-08-27 (latest)\n", out.String())
+}
+
+func TestReplayPinnedRendering(t *testing.T) {
+	unpinned := &versionRecorder{}
+	unpinnedServer := httptest.NewServer(unpinned)
+	defer unpinnedServer.Close()
+
+	pinned := &versionRecorder{}
+	pinnedServer := httptest.NewServer(pinned)
+	defer pinnedServer.Close()
+
+	p, err := New(&Config{
+		EndpointRoutes: []EndpointRoute{
+			{URL: unpinnedServer.URL, EventTypes: []string{"*"}},
+			{URL: pinnedServer.URL, EventTypes: []string{"*"}, APIVersion: "2020-08-27"},
+		},
+	}, []string{"*"})
+	require.NoError(t, err)
+
+	deliverRenderings(p, "evt_1", "2019-05-16", "2020-08-27")
+
+	p.dispatcher.close()
+	require.Equal(t, 0, p.dispatcher.wait(time.Second))
+
+	latest := "2020-08-27"
+
+	// The latest rendering was only forwarded to the pinned endpoint, and is
+	// only resent to it
+	err = p.Replay([]*JournalEntry{
+		{
+			WebhookID:    "wh_latest",
+			APIVersion:   &latest,
+			EventPayload: `{"id":"evt_1","type":"customer.created","api_version":"2020-08-27"}`,
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"2019-05-16"}, unpinned.versions)
+	require.Equal(t, []string{"2020-08-27", "2020-08-27"}, pinned.versions)
+}
@@ -201,3 +201,12 @@ func TestCreateOrNormalizePath(t *testing.T) {
 	result, _ = createOrNormalizePath("charges")
 	require.Equal(t, "/v1/charges", result)
 }
+
+func TestCollapseObjectIDs(t *testing.T) {
+	require.Equal(t, "/v1/customers/*", CollapseObjectIDs("/v1/customers/cus_123"))
+	require.Equal(t, "/v1/payment_intents/*/confirm", CollapseObjectIDs("/v1/payment_intents/pi_test_1aB2/confirm"))
+	require.Equal(t, "/v1/customers/*/sources/*", CollapseObjectIDs("/v1/customers/cus_123/sources/src_456?expand[]=customer"))
+	require.Equal(t, "/v1/file_links", CollapseObjectIDs("/v1/file_links"))
+	require.Equal(t, "/v1/prices/price_123", CollapseObjectIDs("/v1/prices/price_123"))
+	require.Equal(t, "/v1/customers", CollapseObjectIDs("/v1/customers"))
+}
@@ -0,0 +1,42 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// EventList contains a page of events of the account
+type EventList struct {
+	Data    []json.RawMessage `json:"data"`
+	HasMore bool              `json:"has_more"`
+}
+
+// EventsList returns a page of up to 100 events created at or after
+// createdGTE (a Unix timestamp), most recent first. The page starts after the
+// event startingAfter if it is not empty. Events are rendered with apiVersion,
+// or with the account's default API version if it is empty.
+func EventsList(baseURL, apiVersion, apiKey string, createdGTE int64, startingAfter string) (EventList, error) {
+	params := &RequestParameters{
+		data:          []string{"limit=100", fmt.Sprintf("created[gte]=%d", createdGTE)},
+		startingAfter: startingAfter,
+		version:       apiVersion,
+	}
+
+	base := &Base{
+		Method:         http.MethodGet,
+		SuppressOutput: true,
+		APIBaseURL:     baseURL,
+	}
+
+	data := EventList{}
+
+	resp, err := base.MakeRequest(apiKey, "/v1/events", params, true)
+	if err != nil {
+		return data, err
+	}
+
+	err = json.Unmarshal(resp, &data)
+
+	return data, err
+}
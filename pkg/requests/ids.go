@@ -1,6 +1,9 @@
 package requests
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+)
 
 var idURLMap = map[string]string{
 	"acct":  "/v1/accounts/",
@@ -51,3 +54,31 @@ var idURLMap = map[string]string{
 }
 
 var idRegex = regexp.MustCompile("^([a-z]{2,5})_(test_|live_)?[a-zA-Z0-9]{3,}$")
+
+// idCharsRegex tells object IDs apart from path segments such as file_links,
+// since IDs contain digits or uppercase letters
+var idCharsRegex = regexp.MustCompile("[A-Z0-9]")
+
+// CollapseObjectIDs replaces the IDs of known objects in a request path with
+// *, e.g. /v1/payment_intents/pi_123/confirm becomes
+// /v1/payment_intents/*/confirm. The query string, if any, is removed.
+func CollapseObjectIDs(path string) string {
+	if i := strings.IndexByte(path, '?'); i != -1 {
+		path = path[:i]
+	}
+
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		matches := idRegex.FindStringSubmatch(segment)
+		if matches == nil || !idCharsRegex.MatchString(segment) {
+			continue
+		}
+
+		if _, ok := idURLMap[matches[1]]; ok {
+			segments[i] = "*"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
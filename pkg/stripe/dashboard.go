@@ -0,0 +1,20 @@
+package stripe
+
+import "fmt"
+
+// DashboardURL returns the base URL of the dashboard pages for the given mode
+// and, if set, Connect account. For example, the page of an event is at
+// DashboardURL(livemode, account) + "/events/" + eventID.
+func DashboardURL(livemode bool, account string) string {
+	maybeTest := ""
+	if !livemode {
+		maybeTest = "/test"
+	}
+
+	maybeAccount := ""
+	if account != "" {
+		maybeAccount = fmt.Sprintf("/%s", account)
+	}
+
+	return fmt.Sprintf("%s%s%s", DefaultDashboardBaseURL, maybeAccount, maybeTest)
+}
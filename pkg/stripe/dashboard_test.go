@@ -0,0 +1,14 @@
+package stripe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardURL(t *testing.T) {
+	require.Equal(t, "https://dashboard.stripe.com/test", DashboardURL(false, ""))
+	require.Equal(t, "https://dashboard.stripe.com/acct_123/test", DashboardURL(false, "acct_123"))
+	require.Equal(t, "https://dashboard.stripe.com", DashboardURL(true, ""))
+	require.Equal(t, "https://dashboard.stripe.com/acct_123", DashboardURL(true, "acct_123"))
+}
@@ -123,6 +123,16 @@ func RequestStatus(status string) error {
 	return fmt.Errorf("%s is not an acceptable request status (SUCCEEDED, FAILED)", status)
 }
 
+// WebhookSigningSecret validates that a string looks like a webhook endpoint
+// signing secret.
+func WebhookSigningSecret(secret string) error {
+	if !strings.HasPrefix(secret, "whsec_") || len(secret) <= len("whsec_") {
+		return errors.New("the webhook signing secret provided is invalid, it must start with whsec_")
+	}
+
+	return nil
+}
+
 // StatusCode validates that a provided status code is within the range of
 // those used in the Stripe API.
 func StatusCode(code string) error {
@@ -111,3 +111,10 @@ func TestStatusCodeNotXs(t *testing.T) {
 	err := StatusCodeType("201")
 	require.Equal(t, "Provided status code type 201 is not a valid type (2XX, 4XX, 5XX)", fmt.Sprintf("%s", err))
 }
+
+func TestWebhookSigningSecret(t *testing.T) {
+	require.NoError(t, WebhookSigningSecret("whsec_abc123"))
+
+	require.Error(t, WebhookSigningSecret("whsec_"))
+	require.Error(t, WebhookSigningSecret("sk_test_123"))
+}
@@ -0,0 +1,182 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// Public types
+//
+
+// SignedHeader is a parsed `Stripe-Signature` header.
+type SignedHeader struct {
+	Timestamp time.Time
+
+	// Signatures maps signature schemes (e.g. "v1") to the signatures
+	// provided for that scheme
+	Signatures map[string][]string
+}
+
+// VerificationResult describes the outcome of verifying a signature header.
+type VerificationResult struct {
+	// Valid is true if a signature matched and the timestamp is within tolerance
+	Valid bool
+
+	// MatchedScheme is the scheme of the matching signature, if any
+	MatchedScheme string
+
+	// Schemes lists all the signature schemes present in the header
+	Schemes []string
+
+	Timestamp time.Time
+
+	// Age is the time elapsed between the signature's timestamp and the
+	// verification
+	Age time.Duration
+
+	// OutsideTolerance is true if the timestamp is too old (or too far in
+	// the future)
+	OutsideTolerance bool
+}
+
+//
+// Public variables
+//
+
+// ErrInvalidHeader is returned when a signature header can't be parsed.
+var ErrInvalidHeader = errors.New("Stripe-Signature header has an invalid format, expected t=<timestamp>,v1=<signature>")
+
+// ErrNoSignatures is returned when a signature header doesn't contain any
+// signature.
+var ErrNoSignatures = errors.New("Stripe-Signature header does not contain any signatures")
+
+//
+// Public constants
+//
+
+// SignatureHeader is the name of the HTTP header carrying webhook signatures.
+const SignatureHeader = "Stripe-Signature"
+
+// SigningScheme is the signature scheme used by Stripe to sign webhooks.
+const SigningScheme = "v1"
+
+// DefaultTolerance is the default maximum age of a signature, matching the
+// default of Stripe's official libraries.
+const DefaultTolerance = 300 * time.Second
+
+//
+// Public functions
+//
+
+// ComputeSignature computes a webhook signature for the given payload and
+// timestamp, using the webhook endpoint's signing secret.
+func ComputeSignature(t time.Time, payload []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d", t.Unix())))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
+
+// GenerateSignatureHeader returns the value of a `Stripe-Signature` header
+// for the given payload, signed at time t with secret.
+func GenerateSignatureHeader(t time.Time, payload []byte, secret string) string {
+	signature := ComputeSignature(t, payload, secret)
+
+	return fmt.Sprintf("t=%d,%s=%s", t.Unix(), SigningScheme, hex.EncodeToString(signature))
+}
+
+// ParseSignatureHeader parses a `Stripe-Signature` header into its timestamp
+// and the signatures it contains, grouped by scheme.
+func ParseSignatureHeader(header string) (*SignedHeader, error) {
+	sh := &SignedHeader{
+		Signatures: make(map[string][]string),
+	}
+
+	timestampFound := false
+
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			return nil, ErrInvalidHeader
+		}
+
+		if parts[0] == "t" {
+			timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return nil, ErrInvalidHeader
+			}
+
+			sh.Timestamp = time.Unix(timestamp, 0)
+			timestampFound = true
+
+			continue
+		}
+
+		sh.Signatures[parts[0]] = append(sh.Signatures[parts[0]], parts[1])
+	}
+
+	if !timestampFound {
+		return nil, ErrInvalidHeader
+	}
+
+	if len(sh.Signatures) == 0 {
+		return nil, ErrNoSignatures
+	}
+
+	return sh, nil
+}
+
+// VerifySignatureHeader checks a `Stripe-Signature` header against a payload
+// and signing secret. The returned result describes the verification in
+// detail; its Valid field is only true if a signature matched and the
+// timestamp is within tolerance of now. A tolerance of 0 disables the
+// timestamp check.
+func VerifySignatureHeader(payload []byte, header string, secret string, tolerance time.Duration, now time.Time) (*VerificationResult, error) {
+	sh, err := ParseSignatureHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerificationResult{
+		Timestamp: sh.Timestamp,
+		Age:       now.Sub(sh.Timestamp),
+	}
+
+	for scheme := range sh.Signatures {
+		result.Schemes = append(result.Schemes, scheme)
+	}
+
+	sort.Strings(result.Schemes)
+
+	expected := ComputeSignature(sh.Timestamp, payload, secret)
+
+	for _, signature := range sh.Signatures[SigningScheme] {
+		decoded, err := hex.DecodeString(signature)
+		if err != nil {
+			continue
+		}
+
+		if hmac.Equal(expected, decoded) {
+			result.MatchedScheme = SigningScheme
+			break
+		}
+	}
+
+	if tolerance > 0 && (result.Age > tolerance || result.Age < -tolerance) {
+		result.OutsideTolerance = true
+	}
+
+	result.Valid = result.MatchedScheme != "" && !result.OutsideTolerance
+
+	return result, nil
+}
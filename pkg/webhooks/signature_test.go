@@ -0,0 +1,87 @@
+package webhooks
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeSignature(t *testing.T) {
+	signature := ComputeSignature(time.Unix(1583423541, 0), []byte(`{"id":"evt_123"}`), "whsec_test_secret")
+
+	// Computed with: echo -n '1583423541.{"id":"evt_123"}' | openssl dgst -sha256 -hmac whsec_test_secret
+	require.Equal(t, "c9e173342e563745002217c81098becf651cce681883bfa311f97af9fb8a679e", hex.EncodeToString(signature))
+}
+
+func TestGenerateSignatureHeader(t *testing.T) {
+	ts := time.Unix(1583423541, 0)
+	payload := []byte(`{"id":"evt_123"}`)
+
+	header := GenerateSignatureHeader(ts, payload, "whsec_test_secret")
+	require.Equal(t, "t=1583423541,v1="+hex.EncodeToString(ComputeSignature(ts, payload, "whsec_test_secret")), header)
+}
+
+func TestParseSignatureHeader(t *testing.T) {
+	sh, err := ParseSignatureHeader("t=1583423541,v1=abc,v1=def,v0=ghi")
+	require.NoError(t, err)
+	require.Equal(t, int64(1583423541), sh.Timestamp.Unix())
+	require.Equal(t, []string{"abc", "def"}, sh.Signatures["v1"])
+	require.Equal(t, []string{"ghi"}, sh.Signatures["v0"])
+
+	_, err = ParseSignatureHeader("v1=abc")
+	require.Equal(t, ErrInvalidHeader, err)
+
+	_, err = ParseSignatureHeader("t=notanumber,v1=abc")
+	require.Equal(t, ErrInvalidHeader, err)
+
+	_, err = ParseSignatureHeader("garbage")
+	require.Equal(t, ErrInvalidHeader, err)
+
+	_, err = ParseSignatureHeader("t=1583423541")
+	require.Equal(t, ErrNoSignatures, err)
+}
+
+func TestVerifySignatureHeader(t *testing.T) {
+	ts := time.Unix(1583423541, 0)
+	payload := []byte(`{"id":"evt_123"}`)
+	header := GenerateSignatureHeader(ts, payload, "whsec_test_secret")
+
+	result, err := VerifySignatureHeader(payload, header, "whsec_test_secret", DefaultTolerance, ts.Add(10*time.Second))
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+	require.Equal(t, "v1", result.MatchedScheme)
+	require.Equal(t, []string{"v1"}, result.Schemes)
+	require.Equal(t, 10*time.Second, result.Age)
+	require.False(t, result.OutsideTolerance)
+
+	// Wrong secret
+	result, err = VerifySignatureHeader(payload, header, "whsec_other_secret", DefaultTolerance, ts)
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.Equal(t, "", result.MatchedScheme)
+
+	// Modified payload
+	result, err = VerifySignatureHeader([]byte(`{"id":"evt_456"}`), header, "whsec_test_secret", DefaultTolerance, ts)
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+
+	// Expired timestamp
+	result, err = VerifySignatureHeader(payload, header, "whsec_test_secret", DefaultTolerance, ts.Add(time.Hour))
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.Equal(t, "v1", result.MatchedScheme)
+	require.True(t, result.OutsideTolerance)
+
+	// Tolerance check disabled
+	result, err = VerifySignatureHeader(payload, header, "whsec_test_secret", 0, ts.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+
+	// Additional signatures for other secrets or schemes are ignored
+	result, err = VerifySignatureHeader(payload, header+",v1=deadbeef,v0=abc", "whsec_test_secret", DefaultTolerance, ts)
+	require.NoError(t, err)
+	require.True(t, result.Valid)
+	require.Equal(t, []string{"v0", "v1"}, result.Schemes)
+}
@@ -43,6 +43,10 @@ type Config struct {
 	WriteWait time.Duration
 
 	EventHandler EventHandler
+
+	// ConnectionHandler, if set, is notified when the connection with Stripe
+	// is lost and when it is established again
+	ConnectionHandler ConnectionHandler
 }
 
 // EventHandler handles an event.
@@ -61,6 +65,26 @@ func (f EventHandlerFunc) ProcessEvent(msg IncomingMessage) {
 	f(msg)
 }
 
+// ConnectionHandler handles changes in the state of the websocket
+// connection. It is called with connected set to false and the cause of the
+// disconnection when the connection is lost unexpectedly, then with connected
+// set to true once the client has reconnected. The periodic resets of the
+// connection are not reported.
+type ConnectionHandler interface {
+	ProcessConnectionChange(connected bool, err error)
+}
+
+// ConnectionHandlerFunc is an adapter to allow the use of ordinary
+// functions as connection handlers. If f is a function with the
+// appropriate signature, ConnectionHandlerFunc(f) is a
+// ConnectionHandler that calls f.
+type ConnectionHandlerFunc func(connected bool, err error)
+
+// ProcessConnectionChange calls f(connected, err).
+func (f ConnectionHandlerFunc) ProcessConnectionChange(connected bool, err error) {
+	f(connected, err)
+}
+
 // Client is the client used to receive webhook requests from Stripe
 // and send back webhook responses from the local endpoint to Stripe.
 type Client struct {
@@ -105,6 +129,10 @@ func (c *Client) Connected() <-chan struct{} {
 
 // Run starts listening for incoming webhook requests from Stripe.
 func (c *Client) Run(ctx context.Context) {
+	// disconnected is set when the connection was lost unexpectedly, until
+	// the client reconnects
+	disconnected := false
+
 	for {
 		c.isConnected = false
 		c.cfg.Log.WithFields(log.Fields{
@@ -139,6 +167,11 @@ func (c *Client) Run(ctx context.Context) {
 			err = c.connect(ctx)
 		}
 
+		if disconnected {
+			disconnected = false
+			c.cfg.ConnectionHandler.ProcessConnectionChange(true, nil)
+		}
+
 		select {
 		case <-ctx.Done():
 			close(c.send)
@@ -153,13 +186,16 @@ func (c *Client) Run(ctx context.Context) {
 			close(c.NotifyExpired)
 
 			return
-		case <-c.notifyClose:
+		case err := <-c.notifyClose:
 			c.cfg.Log.WithFields(log.Fields{
 				"prefix": "websocket.client.Run",
 			}).Debug("Disconnected from Stripe")
 			close(c.stopReadPump)
 			close(c.stopWritePump)
 			c.wg.Wait()
+
+			disconnected = true
+			c.cfg.ConnectionHandler.ProcessConnectionChange(false, err)
 		case <-time.After(c.cfg.ReconnectInterval):
 			c.cfg.Log.WithFields(log.Fields{
 				"prefix": "websocket.Client.Run",
@@ -481,6 +517,10 @@ func NewClient(url string, webSocketID string, websocketAuthorizedFeature string
 		cfg.EventHandler = nullEventHandler
 	}
 
+	if cfg.ConnectionHandler == nil {
+		cfg.ConnectionHandler = nullConnectionHandler
+	}
+
 	return &Client{
 		URL:                        url,
 		WebSocketID:                webSocketID,
@@ -514,6 +554,8 @@ var subprotocols = [...]string{"stripecli-devproxy-v1"}
 
 var nullEventHandler = EventHandlerFunc(func(IncomingMessage) {})
 
+var nullConnectionHandler = ConnectionHandlerFunc(func(bool, error) {})
+
 //
 // Private functions
 //
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -174,3 +175,61 @@ func TestClientExpiredError(t *testing.T) {
 		require.FailNow(t, "Timed out waiting for response from test server")
 	}
 }
+
+func TestClientConnectionHandler(t *testing.T) {
+	var connections int32
+
+	upgrader := ws.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+
+		// Drop the first connection, keep the next one open
+		if atomic.AddInt32(&connections, 1) == 1 {
+			c.Close()
+			return
+		}
+
+		defer c.Close()
+
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	changes := make(chan bool)
+
+	client := NewClient(
+		url,
+		"websocket-random-id",
+		"webhook-payloads",
+		&Config{
+			ConnectAttemptWait: 1,
+			ConnectionHandler: ConnectionHandlerFunc(func(connected bool, err error) {
+				if !connected {
+					require.Error(t, err)
+				}
+				changes <- connected
+			}),
+		},
+	)
+
+	go client.Run(context.Background())
+
+	defer client.Stop()
+
+	for _, expected := range []bool{false, true} {
+		select {
+		case connected := <-changes:
+			require.Equal(t, expected, connected)
+		case <-time.After(500 * time.Millisecond):
+			require.FailNow(t, "Timed out waiting for connection change")
+		}
+	}
+}